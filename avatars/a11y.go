@@ -0,0 +1,52 @@
+package avatars
+
+import (
+	"fmt"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// anonymousTitle is the title of avatars with a hidden name
+const anonymousTitle = "Avatar"
+
+// Accessibility defines the accessible text alternatives of the avatar:
+// a <title> (and an optional <desc>) the SVG is labelled by, for screen readers
+type Accessibility struct {
+	// The avatar title. Defaults to the name
+	Title string
+
+	// Flag indicating if the name should be kept out of the default title, for privacy.
+	// The title is then a generic "Avatar". It has no effect if Title is set
+	HideName bool
+
+	// The optional avatar description
+	Desc string
+}
+
+// labelScene adds the accessible labels to the avatar scene.
+// The label IDs are derived like the mask ones, so they're prefixed along with them
+func labelScene(s *scene.Scene, style Style, seed int, name string, a Accessibility) {
+	s.Title = &scene.Label{
+		ID:   fmt.Sprintf("title_%s_%d", style, seed),
+		Text: a.title(name),
+	}
+
+	if a.Desc != "" {
+		s.Desc = &scene.Label{
+			ID:   fmt.Sprintf("desc_%s_%d", style, seed),
+			Text: a.Desc,
+		}
+	}
+}
+
+// title returns the resolved avatar title, for the given name
+func (a Accessibility) title(name string) string {
+	switch {
+	case a.Title != "":
+		return a.Title
+	case a.HideName:
+		return anonymousTitle
+	default:
+		return name
+	}
+}
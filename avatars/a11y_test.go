@@ -0,0 +1,66 @@
+package avatars
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Accessibility(t *testing.T) {
+	t.Parallel()
+
+	render := func(t *testing.T, opts Options) string {
+		t.Helper()
+
+		svg, err := Render(opts)
+		require.NoError(t, err)
+
+		return svg
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		svg := render(t, Options{Name: "Grace"})
+
+		assert.NotContains(t, svg, "<title")
+		assert.NotContains(t, svg, "aria-")
+	})
+
+	t.Run("name title", func(t *testing.T) {
+		t.Parallel()
+
+		svg := render(t, Options{Style: Beam, Name: "Grace <Hopper>", Accessibility: &Accessibility{}})
+		id := "title_beam_" + strconv.Itoa(NameToID("Grace <Hopper>"))
+
+		assert.Contains(t, svg, `role="img" xmlns="http://www.w3.org/2000/svg" aria-labelledby="`+id+`"`)
+		assert.Contains(t, svg, `><title id="`+id+`">Grace &lt;Hopper&gt;</title><mask`)
+		assert.NotContains(t, svg, "<desc")
+	})
+
+	t.Run("custom title and description", func(t *testing.T) {
+		t.Parallel()
+
+		svg := render(t, Options{
+			Name:          "Grace",
+			IDPrefix:      "p_",
+			Accessibility: &Accessibility{Title: "Grace's avatar", Desc: "A marble avatar"},
+		})
+
+		assert.Contains(t, svg, `aria-labelledby="p_title_marble_`)
+		assert.Contains(t, svg, `aria-describedby="p_desc_marble_`)
+		assert.Contains(t, svg, `>Grace's avatar</title><desc id="p_desc_marble_`)
+		assert.Contains(t, svg, `>A marble avatar</desc>`)
+	})
+
+	t.Run("hidden name", func(t *testing.T) {
+		t.Parallel()
+
+		svg := render(t, Options{Name: "grace@example.com", Accessibility: &Accessibility{HideName: true}})
+
+		assert.Contains(t, svg, `>Avatar</title>`)
+		assert.NotContains(t, svg, "grace")
+	})
+}
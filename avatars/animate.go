@@ -0,0 +1,215 @@
+package avatars
+
+import (
+	"fmt"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// Animator is an optional Generator extension, adding looping motion to the avatar content.
+// The motion is derived from the seed, so animated avatars are deterministic as well
+type Animator interface {
+	// Animate returns the drawn avatar content, with its moving nodes wrapped
+	// in animated groups (see scene.Animation)
+	Animate(seed int, palette Palette, nodes []scene.Node) []scene.Node
+}
+
+// animated wraps the nodes in a group running the animation
+func animated(a *scene.Animation, nodes ...scene.Node) *scene.Group {
+	return &scene.Group{
+		Animation: a,
+		Children:  nodes,
+	}
+}
+
+// animateScene adds the style's motion to the masked avatar content.
+// Styles without an Animator stay still
+func animateScene(s *scene.Scene, style Style, seed int, palette Palette) {
+	group, ok := s.Children[0].(*scene.Group) // the masked avatar content
+	if !ok {
+		return
+	}
+
+	gen, ok := lookup(style)
+	if !ok {
+		gen = marbleGenerator
+	}
+
+	a, ok := gen.(Animator)
+	if !ok {
+		return
+	}
+
+	if len(palette) == 0 {
+		palette = DefaultPalette
+	}
+
+	group.Children = a.Animate(seed, palette, group.Children)
+}
+
+// animateMarble makes both blobs drift and rotate around their place, out of step
+func animateMarble(id int, _ Palette, nodes []scene.Node) []scene.Node {
+	for i := 1; i <= 2; i++ {
+		angle := float64(10 + 2*IDToDigit(id, i)) // 10-28deg
+		if IDToBoolean(id, i+2) {
+			angle = -angle
+		}
+
+		nodes[i] = animated(&scene.Animation{
+			ID:        fmt.Sprintf("anim_drift%d_marble_%d", i, id),
+			Duration:  float64(8 + IDToDigit(id, i+1)), // 8-17s
+			Delay:     -float64(IDToDigit(id, i+3)),
+			Alternate: true,
+			Keyframes: []scene.Keyframe{
+				{
+					Offset: 1,
+					Transform: scene.Transform{
+						scene.Translate{X: float64(IDToDigit(id, i)%5-2) * 2, Y: float64(IDToDigit(id, i+1)%5-2) * 2},
+						scene.Rotate{Angle: angle},
+					},
+				},
+			},
+		}, nodes[i])
+	}
+
+	return nodes
+}
+
+// animateBeam makes the face blink every few seconds
+func animateBeam(id int, _ Palette, nodes []scene.Node) []scene.Node {
+	var (
+		face  = nodes[2].(*scene.Group) // mouth, and both eyes
+		open  = scene.Transform{scene.ScaleXY{X: 1, Y: 1}}
+		blink = &scene.Animation{
+			ID:       fmt.Sprintf("anim_blink_beam_%d", id),
+			Duration: float64(3 + IDToDigit(id, 1)%4), // 3-6s
+			Delay:    -float64(IDToDigit(id, 2) % 3),
+			Keyframes: []scene.Keyframe{
+				{Offset: 0.9, Transform: open},
+				{Offset: 0.95, Transform: scene.Transform{scene.ScaleXY{X: 1, Y: 0.1}}},
+				{Offset: 1, Transform: open},
+			},
+		}
+	)
+
+	face.Children = []scene.Node{
+		face.Children[0],
+		animated(blink, face.Children[1:]...),
+	}
+
+	return nodes
+}
+
+// animateBauhaus slowly swings the rectangle and the line, while the circle drifts
+func animateBauhaus(id int, _ Palette, nodes []scene.Node) []scene.Node {
+	swing := func(i int) {
+		angle := float64(15 + 3*IDToDigit(id, i)) // 15-42deg
+		if IDToBoolean(id, i+1) {
+			angle = -angle
+		}
+
+		nodes[i] = animated(&scene.Animation{
+			ID:        fmt.Sprintf("anim_swing%d_bauhaus_%d", i, id),
+			Duration:  float64(6 + IDToDigit(id, i+2)), // 6-15s
+			Delay:     -float64(IDToDigit(id, i)),
+			Alternate: true,
+			Keyframes: []scene.Keyframe{
+				{Offset: 1, Transform: scene.Transform{scene.Rotate{Angle: angle}}},
+			},
+		}, nodes[i])
+	}
+
+	swing(1) // rectangle
+	swing(3) // line
+
+	// Circle
+	nodes[2] = animated(&scene.Animation{
+		ID:        fmt.Sprintf("anim_drift_bauhaus_%d", id),
+		Duration:  float64(5 + IDToDigit(id, 4)), // 5-14s
+		Delay:     -float64(IDToDigit(id, 5)),
+		Alternate: true,
+		Keyframes: []scene.Keyframe{
+			{
+				Offset: 1,
+				Transform: scene.Transform{
+					scene.Translate{X: float64(IDToPoint(id, 9, 1)), Y: float64(IDToPoint(id, 9, 2))},
+				},
+			},
+		},
+	}, nodes[2])
+
+	return nodes
+}
+
+// animatePixel makes a few pixels fade into the color of another pixel, and back
+func animatePixel(id int, _ Palette, nodes []scene.Node) []scene.Node {
+	const twinkles = 6
+
+	for k := range twinkles {
+		var (
+			i        = (id/(k+1) + 11*k) % pixelElements
+			pixel, _ = nodes[i].(*scene.Rect)
+			other, _ = nodes[(i+1+id%7)%pixelElements].(*scene.Rect)
+		)
+
+		// Skip pixels that were already picked, or wouldn't change color
+		if pixel == nil || other == nil || pixel.Fill == other.Fill {
+			continue
+		}
+
+		// The original pixel fades out, uncovering a copy in the other color
+		under := *pixel
+		under.Fill = other.Fill
+
+		nodes[i] = &scene.Group{
+			Children: []scene.Node{
+				&under,
+				animated(&scene.Animation{
+					ID:       fmt.Sprintf("anim_twinkle%d_pixel_%d", k, id),
+					Duration: float64(4 + IDToDigit(id, k)%4), // 4-7s
+					Delay:    -float64(k),
+					Keyframes: []scene.Keyframe{
+						{Offset: 0.5, Fade: 1},
+					},
+				}, pixel),
+			},
+		}
+	}
+
+	return nodes
+}
+
+// animateRing spins the concentric rings around the center, at a constant pace
+func animateRing(id int, _ Palette, nodes []scene.Node) []scene.Node {
+	angle := 360.0
+	if IDToBoolean(id, 1) {
+		angle = -angle
+	}
+
+	spin := animated(&scene.Animation{
+		ID:       fmt.Sprintf("anim_spin_ring_%d", id),
+		Duration: float64(12 + IDToDigit(id, 2)), // 12-21s
+		Linear:   true,
+		Keyframes: []scene.Keyframe{
+			{Offset: 1, Transform: scene.Transform{scene.Rotate{Angle: angle}}},
+		},
+	}, nodes[2:8]...) // the halves stay put, so square avatars keep their corners
+
+	return []scene.Node{nodes[0], nodes[1], spin, nodes[8]}
+}
+
+// animateSunset makes the horizon rise and set, by stretching the lower band upwards
+func animateSunset(id int, _ Palette, nodes []scene.Node) []scene.Node {
+	nodes[1] = animated(&scene.Animation{
+		ID:        fmt.Sprintf("anim_horizon_sunset_%d", id),
+		Duration:  float64(6 + IDToDigit(id, 1)%5), // 6-10s
+		Delay:     -float64(IDToDigit(id, 2)),
+		Alternate: true,
+		Origin:    "bottom",
+		Keyframes: []scene.Keyframe{
+			{Offset: 1, Transform: scene.Transform{scene.ScaleXY{X: 1, Y: 1.1 + 0.05*float64(IDToDigit(id, 3)%3)}}},
+		},
+	}, nodes[1])
+
+	return nodes
+}
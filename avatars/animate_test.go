@@ -0,0 +1,80 @@
+package avatars
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Animate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			svg, err := Render(Options{Style: style, Name: "Grace Hopper"})
+			require.NoError(t, err)
+
+			assert.NotContains(t, svg, "<style>", style)
+		}
+	})
+
+	t.Run("every style", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			opts := Options{Style: style, Name: "Grace Hopper", Animate: true}
+
+			svg, err := Render(opts)
+			require.NoError(t, err)
+
+			assert.Contains(t, svg, `<g class="anim_`, style)
+			assert.Contains(t, svg, "@keyframes anim_", style)
+			assert.Contains(t, svg, "@media (prefers-reduced-motion:reduce)", style)
+
+			// The motion is derived from the seed
+			again, err := Render(opts)
+			require.NoError(t, err)
+
+			assert.Equal(t, svg, again, style)
+		}
+	})
+
+	t.Run("seed-derived motion", func(t *testing.T) {
+		t.Parallel()
+
+		motion := func(name string) string {
+			svg, err := Render(Options{Style: Marble, Name: name, Animate: true})
+			require.NoError(t, err)
+
+			_, css, _ := strings.Cut(svg, "<style>")
+
+			return strings.ReplaceAll(css, "_"+strconv.Itoa(NameToID(name)), "")
+		}
+
+		assert.NotEqual(t, motion("Grace Hopper"), motion("Ada Lovelace"))
+	})
+
+	t.Run("prefixed IDs", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Style: Ring, Name: "Grace Hopper", Animate: true, IDPrefix: "a1_"})
+		require.NoError(t, err)
+
+		assert.Contains(t, svg, `<g class="a1_anim_spin_ring_`)
+		assert.Contains(t, svg, "@keyframes a1_anim_spin_ring_")
+	})
+
+	t.Run("initials stay still", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Style: Beam, Name: "Grace Hopper", Animate: true, Initials: true})
+		require.NoError(t, err)
+
+		assert.Contains(t, svg, ">GH</text></g><style>")
+	})
+}
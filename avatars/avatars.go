@@ -2,9 +2,19 @@ package avatars
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
 )
 
-var ErrUnknownStyle = errors.New("unknown style")
+var (
+	ErrUnknownStyle = errors.New("unknown style")
+	ErrInvalidColor = errors.New("invalid color, expected #RRGGBB hex")
+	ErrInvalidSize  = errors.New("invalid size, expected a non-negative value")
+)
 
 type (
 	// Style represents a distinct avatar style
@@ -18,6 +28,11 @@ var DefaultPalette = Palette{
 	"#FFB703", "#219EBC", "#8ECAE6", "#023047", "#FB8500",
 }
 
+// OutputVersion is the version of the generated output. It's bumped whenever
+// the same options start rendering a different avatar, so caches keyed on
+// the options (such as HTTP ETags) are invalidated
+const OutputVersion = 1
+
 const (
 	Beam    Style = "beam"
 	Bauhaus Style = "bauhaus"
@@ -27,7 +42,212 @@ const (
 	Sunset  Style = "sunset"
 )
 
-// Generate generates an avatar based on the requested style and params
+// Options defines the avatar render parameters.
+// The zero value renders a round, unsized Marble avatar
+// with the default palette
+type Options struct {
+	// The avatar style. Defaults to Marble if empty
+	Style Style
+
+	// The seed the avatar is deterministically derived from
+	Name string
+
+	// The hex (#RRGGBB) color palette. Defaults to DefaultPalette if empty
+	Palette Palette
+
+	// The hex (#RRGGBB) color palette for viewers preferring a dark color scheme, if any.
+	// The SVG swaps every color through CSS, while raster images keep the main palette
+	DarkPalette Palette
+
+	// The width and height of the SVG, in px.
+	// If 0, the dimensions are omitted and the SVG scales to its container
+	Size int
+
+	// Flag indicating if the avatar should use a square mask instead of a round one.
+	// Shorthand for ShapeSquare, which Shape takes precedence over
+	Square bool
+
+	// The outline the avatar is masked into.
+	// Defaults to ShapeCircle (or ShapeSquare, if Square is set)
+	Shape Shape
+
+	// The corner radius of ShapeRounded, relative to the avatar size, in [0, 0.5].
+	// A radius with no shape implies ShapeRounded
+	Radius float64
+
+	// Flag indicating if the avatar content should be left unmasked, full-bleed over the square canvas,
+	// for compositing it into larger drawings. The border and badge still follow the shape
+	Unmasked bool
+
+	// Flag indicating if the style's full-bleed background (such as the Beam or Bauhaus base fill)
+	// should be left out, for compositing the avatar over other content.
+	// Styles without a separate background (Pixel and Sunset) are drawn in full
+	Transparent bool
+
+	// The hasher deriving the avatar seed from the name.
+	// Defaults to JavaHasher, which matches the reference JS library
+	Hasher Hasher
+
+	// The normalization steps applied to the name before it's hashed.
+	// Defaults to NormalizeNone, which hashes the name as-is
+	Normalize Normalization
+
+	// The prefix of the avatar's mask, filter and gradient IDs.
+	// Avatars embedded in the same document need distinct prefixes,
+	// as the IDs of avatars with the same seed collide otherwise
+	IDPrefix string
+
+	// Flag indicating if the ID prefix should be derived from all the render params
+	// (style, name, palette, size and mask), so differing avatars never share IDs
+	// when inlined into the same document. It follows IDPrefix, if both are set
+	UniqueIDs bool
+
+	// The accessible title and description of the SVG.
+	// If nil, the SVG is only marked as an image, without any text alternative
+	Accessibility *Accessibility
+
+	// Flag indicating if the name's initials should be drawn on top of the avatar,
+	// in the color contrasting the most with the avatar center
+	Initials bool
+
+	// The outline along the edge of the avatar, if any
+	Border *Border
+
+	// The status dot in a corner of the avatar, if any
+	Badge *Badge
+
+	// Flag indicating if the avatar should loop through a subtle, seed-derived motion.
+	// The motion is CSS-based, so it's disabled for viewers preferring reduced motion,
+	// and raster images get the static avatar
+	Animate bool
+}
+
+// Validate validates the render options
+func (o Options) Validate() error {
+	if o.Style != "" && !ValidStyle(o.Style) {
+		return fmt.Errorf("%w: %q", ErrUnknownStyle, o.Style)
+	}
+
+	for _, c := range slices.Concat(o.Palette, o.DarkPalette) {
+		if !ValidColor(c) {
+			return fmt.Errorf("%w: %q", ErrInvalidColor, c)
+		}
+	}
+
+	if o.Size < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidSize, o.Size)
+	}
+
+	// The negated range check rejects NaN radii too
+	if o.Shape != "" && !ValidShape(o.Shape) || !(o.Radius >= 0 && o.Radius <= 0.5) {
+		return fmt.Errorf("%w: %q (radius %g)", ErrInvalidShape, o.Shape, o.Radius)
+	}
+
+	if o.IDPrefix != "" && !ValidIDPrefix(o.IDPrefix) {
+		return fmt.Errorf("%w: %q", ErrInvalidIDPrefix, o.IDPrefix)
+	}
+
+	if o.Border != nil {
+		if err := o.Border.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.Badge != nil {
+		if err := o.Badge.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Build validates the options and builds the structured avatar scene,
+// which can be post-processed before it's encoded
+func Build(opts Options) (*scene.Scene, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		seed  = opts.Seed()
+		shape = opts.outline()
+		s     = build(opts.Style, seed, opts.Palette, opts.Size, shape)
+	)
+
+	style := opts.Style
+	if style == "" {
+		style = Marble
+	}
+
+	// The dark variant shares the geometry, so only its colors are kept
+	if len(opts.DarkPalette) > 0 {
+		s.SetDarkColors(build(opts.Style, seed, opts.DarkPalette, opts.Size, shape))
+	}
+
+	if opts.Animate {
+		animateScene(s, style, seed, opts.Palette)
+	}
+
+	if opts.Unmasked || opts.Transparent {
+		compositeScene(s, style, opts.Unmasked, opts.Transparent)
+	}
+
+	if opts.Initials {
+		addInitials(s, style, seed, opts.Palette, opts.DarkPalette, opts.Name)
+	}
+
+	if opts.Border != nil || opts.Badge != nil {
+		decorateScene(s, style, seed, shape, opts.Border, opts.Badge)
+	}
+
+	if opts.Accessibility != nil {
+		labelScene(s, style, seed, opts.Name, *opts.Accessibility)
+	}
+
+	if prefix := opts.idPrefix(); prefix != "" {
+		s.PrefixIDs(prefix)
+	}
+
+	return s, nil
+}
+
+// Seed returns the seed the avatar is derived from: the hash of the normalized name.
+// Options with the same seed (and other params) render the same avatar
+func (o Options) Seed() int {
+	hasher := o.Hasher
+	if hasher == nil {
+		hasher = JavaHasher{}
+	}
+
+	return hasher.Hash(Normalize(o.Name, o.Normalize))
+}
+
+// Write validates the options and streams the avatar SVG to the given writer.
+// The output is buffered, so w receives it in a few large writes
+func Write(w io.Writer, opts Options) error {
+	s, err := Build(opts)
+	if err != nil {
+		return err
+	}
+
+	return scene.WriteSVG(w, s)
+}
+
+// Render validates the options and generates the avatar SVG
+func Render(opts Options) (string, error) {
+	var b strings.Builder
+
+	if err := Write(&b, opts); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// Generate generates an avatar based on the requested style and params.
+// Unlike Render, it does not validate the params: unknown styles
+// fall back to Marble, and an empty palette falls back to DefaultPalette
 func Generate(
 	style Style,
 	name string,
@@ -35,28 +255,67 @@ func Generate(
 	size int,
 	square bool,
 ) string {
-	switch style {
-	case Beam:
-		return GenerateBeam(name, palette, size, square)
-	case Bauhaus:
-		return GenerateBauhaus(name, palette, size, square)
-	case Pixel:
-		return GeneratePixel(name, palette, size, square)
-	case Ring:
-		return GenerateRing(name, palette, size, square)
-	case Sunset:
-		return GenerateSunset(name, palette, size, square)
-	default:
-		return GenerateMarble(name, palette, size, square)
-	}
-}
-
-// ValidStyle checks if the style is a valid boring avatar variant
-func ValidStyle(style Style) bool {
-	switch style {
-	case Beam, Bauhaus, Marble, Pixel, Ring, Sunset:
-		return true
-	default:
-		return false
+	return generate(style, name, palette, size, square)
+}
+
+// generate generates the avatar SVG, without validating the params
+func generate(style Style, name string, palette Palette, size int, square bool) string {
+	var b strings.Builder
+
+	_ = write(&b, style, name, palette, size, square) // writes to a strings.Builder never fail
+
+	return b.String()
+}
+
+// write streams the avatar SVG, without validating the params
+func write(w io.Writer, style Style, name string, palette Palette, size int, square bool) error {
+	return scene.WriteSVG(w, build(style, NameToID(name), palette, size, squareOutline(square)))
+}
+
+// build builds the avatar scene of the given style, for the seed.
+// Unregistered styles fall back to Marble
+func build(
+	style Style,
+	id int,
+	palette Palette,
+	size int,
+	shape outline,
+) *scene.Scene {
+	gen, ok := lookup(style)
+	if !ok {
+		style, gen = Marble, marbleGenerator
+	}
+
+	if len(palette) == 0 {
+		palette = DefaultPalette
+	}
+
+	var (
+		viewBox  = gen.ViewBox()
+		nodes    = gen.Draw(id, palette)
+		maskType = scene.MaskLuminance
+	)
+
+	if b, ok := gen.(builtin); ok {
+		maskType = b.maskType
+	}
+
+	// The outline mask over the entire canvas
+	mask := shape.node(float64(viewBox), 0, float64(viewBox))
+	scene.AttrsOf(mask).Fill = scene.Color("#FFFFFF")
+
+	return &scene.Scene{
+		ViewBox: float64(viewBox),
+		Size:    size,
+		Children: []scene.Node{
+			&scene.Group{
+				Mask: &scene.Mask{
+					ID:       fmt.Sprintf("mask_%s_%d", style, id),
+					Type:     maskType,
+					Children: []scene.Node{mask},
+				},
+				Children: nodes,
+			},
+		},
 	}
 }
@@ -0,0 +1,279 @@
+package avatars
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unknown style", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Render(Options{Style: "cubist", Name: "Amelia Earhart"})
+
+		assert.ErrorIs(t, err, ErrUnknownStyle)
+	})
+
+	t.Run("invalid palette color", func(t *testing.T) {
+		t.Parallel()
+
+		for _, c := range []string{"", "FFB703", "#FFB70", "#FFB7033", "#GGGGGG", "red"} {
+			_, err := Render(Options{
+				Name:    "Amelia Earhart",
+				Palette: Palette{"#219EBC", c},
+			})
+
+			assert.ErrorIs(t, err, ErrInvalidColor, c)
+		}
+	})
+
+	t.Run("negative size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Render(Options{Name: "Amelia Earhart", Size: -1})
+
+		assert.ErrorIs(t, err, ErrInvalidSize)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Name: "Amelia Earhart"})
+		require.NoError(t, err)
+
+		assert.Equal(t, GenerateMarble("Amelia Earhart", DefaultPalette, 0, false), svg)
+	})
+
+	t.Run("matches Generate", func(t *testing.T) {
+		t.Parallel()
+
+		palette := Palette{"#0a0310", "#49007e", "#ff005b", "#ff7d10", "#ffb238"}
+
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			svg, err := Render(Options{
+				Style:   style,
+				Name:    "Maria Mitchell",
+				Palette: palette,
+				Size:    120,
+				Square:  true,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, Generate(style, "Maria Mitchell", palette, 120, true), svg)
+		}
+	})
+}
+
+// failingWriter is an io.Writer that always errors out
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("matches Render", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			opts := Options{
+				Style: style,
+				Name:  "Grace Hopper",
+				Size:  64,
+			}
+
+			var b bytes.Buffer
+
+			require.NoError(t, Write(&b, opts))
+
+			svg, err := Render(opts)
+			require.NoError(t, err)
+
+			assert.Equal(t, svg, b.String())
+		}
+	})
+
+	t.Run("byte-identical output", func(t *testing.T) {
+		t.Parallel()
+
+		// The output of earlier versions, attribute order included,
+		// so content hashes and caches of the avatars stay valid
+		testTable := []struct {
+			style    Style
+			square   bool
+			expected string
+		}{
+			{
+				Beam,
+				false,
+				`<svg viewBox="0 0 36 36" fill="none" role="img" xmlns="http://www.w3.org/2000/svg">` +
+					`<mask id="mask_beam_629664820" maskUnits="userSpaceOnUse" x="0" y="0" width="36" height="36">` +
+					`<rect width="36" height="36" rx="72" fill="#FFFFFF"/></mask>` +
+					`<g mask="url(#mask_beam_629664820)"><rect width="36" height="36" fill="#023047"/>` +
+					`<rect x="0" y="0" width="36" height="36" ` +
+					`transform="translate(4.00 4.00) rotate(340 18 18) scale(1.10)" fill="#FFB703" rx="36"/>` +
+					`<g transform="translate(-4.00 -1.00) rotate(0 18 18)">` +
+					`<path d="M15 20c2 1 4 1 6 0" stroke="#000000" fill="none" stroke-linecap="round"/>` +
+					`<rect x="14" y="14" width="1.5" height="2" rx="1" stroke="none" fill="#000000"/>` +
+					`<rect x="20" y="14" width="1.5" height="2" rx="1" stroke="none" fill="#000000"/></g></g></svg>`,
+			},
+			{
+				Sunset,
+				true,
+				`<svg viewBox="0 0 80 80" fill="none" role="img" xmlns="http://www.w3.org/2000/svg">` +
+					`<mask id="mask_sunset_629664820" maskUnits="userSpaceOnUse" x="0" y="0" width="80" height="80">` +
+					`<rect width="80" height="80" fill="#FFFFFF"/></mask><g mask="url(#mask_sunset_629664820)">` +
+					`<path fill="url(#gradient_paint0_linear_629664820)" d="M0 0h80v40H0z"/>` +
+					`<path fill="url(#gradient_paint1_linear_629664820)" d="M0 40h80v40H0z"/></g><defs>` +
+					`<linearGradient id="gradient_paint0_linear_629664820" ` +
+					`x1="40" y1="0" x2="40" y2="40" gradientUnits="userSpaceOnUse">` +
+					`<stop stop-color="#FFB703"/><stop offset="1" stop-color="#219EBC"/></linearGradient>` +
+					`<linearGradient id="gradient_paint1_linear_629664820" ` +
+					`x1="40" y1="40" x2="40" y2="80" gradientUnits="userSpaceOnUse">` +
+					`<stop stop-color="#8ECAE6"/><stop offset="1" stop-color="#023047"/></linearGradient></defs>` +
+					`</svg>`,
+			},
+		}
+
+		for _, testCase := range testTable {
+			var b bytes.Buffer
+
+			require.NoError(t, write(&b, testCase.style, "Mary Baker", nil, 0, testCase.square))
+
+			assert.Equal(t, testCase.expected, b.String(), testCase.style)
+		}
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Error(t, Write(failingWriter{}, Options{Name: "Grace Hopper"}))
+	})
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Build(Options{Size: -1})
+
+		assert.ErrorIs(t, err, ErrInvalidSize)
+	})
+
+	t.Run("recolored scene", func(t *testing.T) {
+		t.Parallel()
+
+		opts := Options{Style: Sunset, Name: "Amelia Earhart", Palette: Palette{"#219EBC"}}
+
+		s, err := Build(opts)
+		require.NoError(t, err)
+
+		s.Recolor(func(c string) string {
+			if c == "#219EBC" {
+				return "#FB8500"
+			}
+
+			return c
+		})
+
+		var b bytes.Buffer
+
+		require.NoError(t, scene.WriteSVG(&b, s))
+
+		opts.Palette = Palette{"#FB8500"}
+
+		expected, err := Render(opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, b.String())
+	})
+
+	t.Run("dark palette", func(t *testing.T) {
+		t.Parallel()
+
+		dark := Palette{"#0B132B", "#1C2541", "#3A506B", "#5BC0BE", "#F5F5F5"}
+
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			s, err := Build(Options{Style: style, Name: "Amelia Earhart", DarkPalette: dark})
+			require.NoError(t, err)
+
+			// Swapping in the dark colors yields the avatar of the dark palette
+			swap := func(color *string, dark *string) {
+				if *dark != "" {
+					*color, *dark = *dark, ""
+				}
+			}
+
+			s.Walk(func(n scene.Node) {
+				if a := scene.AttrsOf(n); a != nil {
+					for _, p := range []*scene.Paint{&a.Fill, &a.Stroke} {
+						swap(&p.Color, &p.Dark)
+
+						if p.Gradient != nil {
+							for i := range p.Gradient.Stops {
+								swap(&p.Gradient.Stops[i].Color, &p.Gradient.Stops[i].Dark)
+							}
+						}
+					}
+				}
+			})
+
+			var b bytes.Buffer
+
+			require.NoError(t, scene.WriteSVG(&b, s))
+
+			expected, err := Render(Options{Style: style, Name: "Amelia Earhart", Palette: dark})
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, b.String(), style)
+		}
+	})
+
+	t.Run("dark palette css", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Style: Bauhaus, Name: "Amelia Earhart", DarkPalette: Palette{"#0B132B"}})
+		require.NoError(t, err)
+
+		assert.Contains(t, svg, `class="dark-fill-0B132B"`)
+		assert.Contains(t, svg, `class="dark-stroke-0B132B"`)
+		assert.Contains(t, svg, `<style>@media (prefers-color-scheme:dark){`+
+			`.dark-fill-0B132B{fill:#0B132B}.dark-stroke-0B132B{stroke:#0B132B}}</style>`)
+
+		_, err = Render(Options{Name: "Amelia Earhart", DarkPalette: Palette{"0B132B"}})
+		assert.ErrorIs(t, err, ErrInvalidColor)
+	})
+
+	t.Run("id prefix", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range []Style{Marble, Sunset} {
+			opts := Options{Style: style, Name: "Amelia Earhart"}
+
+			expected, err := Render(opts)
+			require.NoError(t, err)
+
+			opts.IDPrefix = "a1_"
+
+			prefixed, err := Render(opts)
+			require.NoError(t, err)
+
+			// Every ID, and every reference to it, is prefixed
+			expected = strings.NewReplacer(`id="`, `id="a1_`, `url(#`, `url(#a1_`).Replace(expected)
+
+			assert.Equal(t, expected, prefixed, style)
+		}
+	})
+}
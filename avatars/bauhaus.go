@@ -1,8 +1,9 @@
 package avatars
 
 import (
-	"fmt"
-	"strings"
+	"io"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
 )
 
 const (
@@ -28,16 +29,15 @@ func buildBauhausElements(id int, palette Palette) []bauhausElement {
 
 	for i := 0; i < bauhausElements; i++ {
 		var (
-			n    = id + i
-			mult = id * (i + 1)
+			mult = int64(id) * int64(i+1)
 			rng  = bauhausSize/2 - (i + 17)
 		)
 
 		elements[i] = bauhausElement{
-			color:      palette[n%len(palette)],
-			translateX: float64(IDToPoint(mult, rng, 1)),
-			translateY: float64(IDToPoint(mult, rng, 2)),
-			rotate:     IDToPoint(mult, 360, 0),
+			color:      pickColor(palette, id, i),
+			translateX: float64(idToPoint(mult, rng, 1)),
+			translateY: float64(idToPoint(mult, rng, 2)),
+			rotate:     idToPoint(mult, 360, 0),
 			square:     IDToBoolean(id, 2),
 		}
 	}
@@ -45,115 +45,83 @@ func buildBauhausElements(id int, palette Palette) []bauhausElement {
 	return elements
 }
 
-// GenerateBauhaus returns a bauhaus-style avatar SVG
-func GenerateBauhaus(name string, palette Palette, size int, square bool) string {
+// bauhausBackdrop returns the background color
+func bauhausBackdrop(id int, palette Palette) string {
+	return buildBauhausElements(id, palette)[0].color
+}
+
+// drawBauhaus draws the bauhaus-style avatar content
+func drawBauhaus(id int, palette Palette) []scene.Node {
 	var (
-		id     = NameToID(name)
 		props  = buildBauhausElements(id, palette)
-		maskID = fmt.Sprintf("mask_bauhaus_%d", id)
-		center = bauhausSize / 2
-	)
-
-	// Start building out the SVG
-	var b strings.Builder
-
-	if size > 0 {
-		// Custom size
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`,
-			bauhausSize, bauhausSize,
-			size, size,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg">`,
-			bauhausSize, bauhausSize,
-		)
-	}
-
-	// Mask group
-	_, _ = fmt.Fprintf(
-		&b,
-		`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">`,
-		maskID,
-		bauhausSize, bauhausSize,
-	)
-
-	if square {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" fill="#FFFFFF"/>`,
-			bauhausSize, bauhausSize,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" rx="%d" fill="#FFFFFF"/>`,
-			bauhausSize, bauhausSize,
-			bauhausSize*2,
-		)
-	}
-
-	b.WriteString(`</mask>`)
-
-	// Masked group
-	_, _ = fmt.Fprintf(&b, `<g mask="url(#%s)">`, maskID)
-
-	// Background
-	_, _ = fmt.Fprintf(
-		&b,
-		`<rect width="%d" height="%d" fill="%s"/>`,
-		bauhausSize, bauhausSize,
-		props[0].color,
+		center = float64(bauhausSize / 2)
 	)
 
 	// Rotated / translated rectangle
-	height := bauhausSize
+	height := float64(bauhausSize)
 	if !props[1].square {
 		height = bauhausSize / 8 // 10px
 	}
 
-	_, _ = fmt.Fprintf(
-		&b,
-		`<rect x="%d" y="%d" width="%d" height="%d" fill="%s"`+
-			` transform="translate(%.2f %.2f) rotate(%d %d %d)"/>`,
-		(bauhausSize-60)/2, (bauhausSize-20)/2, // 10, 30
-		bauhausSize, height,
-		props[1].color,
-		props[1].translateX, props[1].translateY,
-		props[1].rotate, center, center,
-	)
-
-	// Translated circle
-	_, _ = fmt.Fprintf(
-		&b,
-		`<circle cx="%d" cy="%d" r="%d" fill="%s"`+
-			` transform="translate(%.2f %.2f)"/>`,
-		center, center, bauhausSize/5, // r = 16
-		props[2].color,
-		props[2].translateX, props[2].translateY,
-	)
-
-	// Translated / rotated line
-	_, _ = fmt.Fprintf(
-		&b,
-		`<line x1="0" y1="%d" x2="%d" y2="%d" stroke-width="2" stroke="%s"`+
-			` transform="translate(%.2f %.2f) rotate(%d %d %d)"/>`,
-		center, bauhausSize, center,
-		props[3].color,
-		props[3].translateX, props[3].translateY,
-		props[3].rotate, center, center,
-	)
-
-	// Group closure
-	b.WriteString(`</g>`)
+	return []scene.Node{
+		// Background
+		&scene.Rect{
+			Width:  bauhausSize,
+			Height: bauhausSize,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(props[0].color),
+			},
+		},
+		// Rectangle
+		&scene.Rect{
+			X:      (bauhausSize - 60) / 2, // 10
+			Y:      (bauhausSize - 20) / 2, // 30
+			Width:  bauhausSize,
+			Height: height,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(props[1].color),
+				Transform: scene.Transform{
+					scene.Translate{X: props[1].translateX, Y: props[1].translateY},
+					scene.Rotate{Angle: float64(props[1].rotate), CX: center, CY: center},
+				},
+			},
+		},
+		// Circle
+		&scene.Circle{
+			CX: center,
+			CY: center,
+			R:  bauhausSize / 5, // 16
+			Attrs: scene.Attrs{
+				Fill: scene.Color(props[2].color),
+				Transform: scene.Transform{
+					scene.Translate{X: props[2].translateX, Y: props[2].translateY},
+				},
+			},
+		},
+		// Line
+		&scene.Line{
+			X1: 0,
+			Y1: center,
+			X2: bauhausSize,
+			Y2: center,
+			Attrs: scene.Attrs{
+				StrokeWidth: 2,
+				Stroke:      scene.Color(props[3].color),
+				Transform: scene.Transform{
+					scene.Translate{X: props[3].translateX, Y: props[3].translateY},
+					scene.Rotate{Angle: float64(props[3].rotate), CX: center, CY: center},
+				},
+			},
+		},
+	}
+}
 
-	// Final svg closure
-	b.WriteString(`</svg>`)
+// WriteBauhaus streams a bauhaus-style avatar SVG to the given writer
+func WriteBauhaus(w io.Writer, name string, palette Palette, size int, square bool) error {
+	return write(w, Bauhaus, name, palette, size, square)
+}
 
-	return b.String()
+// GenerateBauhaus returns a bauhaus-style avatar SVG
+func GenerateBauhaus(name string, palette Palette, size int, square bool) string {
+	return generate(Bauhaus, name, palette, size, square)
 }
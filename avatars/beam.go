@@ -2,7 +2,9 @@ package avatars
 
 import (
 	"fmt"
-	"strings"
+	"io"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
 )
 
 const beamSize = 36
@@ -62,7 +64,7 @@ func buildBeamParams(id int, palette Palette) params {
 		colors: colors{
 			wrapper:    wrapperColor,
 			face:       Contrast(wrapperColor),
-			background: palette[(id+13)%len(palette)],
+			background: pickColor(palette, id, 13),
 		},
 		wrapper: wrapper{
 			translateX: wrapperTranslateX,
@@ -97,130 +99,107 @@ func buildBeamParams(id int, palette Palette) params {
 	return p
 }
 
-// GenerateBeam returns a beam-style avatar SVG
-func GenerateBeam(name string, palette Palette, size int, square bool) string {
-	var (
-		id     = NameToID(name)
-		p      = buildBeamParams(id, palette)
-		maskID = fmt.Sprintf("mask_beam_%d", id)
-	)
-
-	// Start building out the SVG
-	var b strings.Builder
-
-	if size > 0 {
-		// Custom size
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`,
-			beamSize, beamSize,
-			size, size,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg">`,
-			beamSize, beamSize,
-		)
-	}
-
-	// Mask group
-	_, _ = fmt.Fprintf(
-		&b,
-		`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">`,
-		maskID,
-		beamSize, beamSize,
-	)
-
-	if square {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" fill="#FFFFFF"/>`,
-			beamSize, beamSize,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" rx="%d" fill="#FFFFFF"/>`,
-			beamSize, beamSize,
-			beamSize*2,
-		)
-	}
+// beamWrapperOrder is the attribute order of the wrapper, as written by earlier versions
+var beamWrapperOrder = []string{"x", "y", "width", "height", "transform", "fill", "rx"}
 
-	b.WriteString(`</mask>`)
-
-	// Masked group
-	_, _ = fmt.Fprintf(&b, `<g mask="url(#%s)">`, maskID)
+// beamBackdrop returns the wrapper color, covering the avatar center
+func beamBackdrop(id int, palette Palette) string {
+	return buildBeamParams(id, palette).colors.wrapper
+}
 
-	// Background
-	_, _ = fmt.Fprintf(
-		&b,
-		`<rect width="%d" height="%d" fill="%s"/>`,
-		beamSize, beamSize,
-		p.colors.background,
+// drawBeam draws the beam-style avatar content
+func drawBeam(id int, palette Palette) []scene.Node {
+	var (
+		p      = buildBeamParams(id, palette)
+		center = float64(beamSize / 2)
 	)
 
 	// Wrapper square / circle
-	wrapperRX := beamSize / 6
+	wrapperRX := float64(beamSize / 6)
 	if p.wrapper.circle {
 		wrapperRX = beamSize
 	}
 
-	_, _ = fmt.Fprintf(
-		&b,
-		`<rect x="0" y="0" width="%d" height="%d"`+
-			` transform="translate(%.2f %.2f) rotate(%d %d %d) scale(%.2f)"`+
-			` fill="%s" rx="%d"/>`,
-		beamSize, beamSize,
-		p.wrapper.translateX, p.wrapper.translateY,
-		p.wrapper.rotate, beamSize/2, beamSize/2,
-		p.wrapper.scale,
-		p.colors.wrapper,
-		wrapperRX,
-	)
-
-	// Face group
-	_, _ = fmt.Fprintf(
-		&b,
-		`<g transform="translate(%.2f %.2f) rotate(%d %d %d)">`,
-		p.face.translateX, p.face.translateY,
-		p.face.rotate, beamSize/2, beamSize/2,
-	)
-
 	// Mouth
+	var mouth scene.Node
 	if p.face.mouthOpen {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<path d="M15 %dc2 1 4 1 6 0" stroke="%s" fill="none" stroke-linecap="round"/>`,
-			19+p.face.mouthSpread, p.colors.face,
-		)
+		mouth = &scene.Path{
+			D: fmt.Sprintf("M15 %dc2 1 4 1 6 0", 19+p.face.mouthSpread),
+			Attrs: scene.Attrs{
+				Stroke:  scene.Color(p.colors.face),
+				Fill:    scene.None,
+				LineCap: scene.CapRound,
+			},
+		}
 	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<path d="M13,%d a1,0.75 0 0,0 10,0" fill="%s"/>`,
-			19+p.face.mouthSpread, p.colors.face,
-		)
+		mouth = &scene.Path{
+			D: fmt.Sprintf("M13,%d a1,0.75 0 0,0 10,0", 19+p.face.mouthSpread),
+			Attrs: scene.Attrs{
+				Fill: scene.Color(p.colors.face),
+			},
+		}
 	}
 
 	// Eyes
-	_, _ = fmt.Fprintf(
-		&b,
-		`<rect x="%d" y="14" width="1.5" height="2" rx="1" stroke="none" fill="%s"/>`,
-		14-p.face.eyeSpread, p.colors.face,
-	)
-	_, _ = fmt.Fprintf(
-		&b,
-		`<rect x="%d" y="14" width="1.5" height="2" rx="1" stroke="none" fill="%s"/>`,
-		20+p.face.eyeSpread, p.colors.face,
-	)
+	eye := func(x int) scene.Node {
+		return &scene.Rect{
+			X:      float64(x),
+			Y:      14,
+			Width:  1.5,
+			Height: 2,
+			RX:     1,
+			Attrs: scene.Attrs{
+				Stroke: scene.None,
+				Fill:   scene.Color(p.colors.face),
+			},
+		}
+	}
 
-	// Group closures
-	b.WriteString(`</g></g>`)
+	return []scene.Node{
+		// Background
+		&scene.Rect{
+			Width:  beamSize,
+			Height: beamSize,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(p.colors.background),
+			},
+		},
+		// Wrapper
+		&scene.Rect{
+			Width:  beamSize,
+			Height: beamSize,
+			RX:     wrapperRX,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(p.colors.wrapper),
+				Transform: scene.Transform{
+					scene.Translate{X: p.wrapper.translateX, Y: p.wrapper.translateY},
+					scene.Rotate{Angle: float64(p.wrapper.rotate), CX: center, CY: center},
+					scene.Scale{Factor: p.wrapper.scale},
+				},
+				Order: beamWrapperOrder,
+			},
+		},
+		// Face
+		&scene.Group{
+			Transform: scene.Transform{
+				scene.Translate{X: p.face.translateX, Y: p.face.translateY},
+				scene.Rotate{Angle: float64(p.face.rotate), CX: center, CY: center},
+			},
+			Children: []scene.Node{
+				mouth,
+				eye(14 - p.face.eyeSpread),
+				eye(20 + p.face.eyeSpread),
+			},
+		},
+	}
+}
 
-	// Final svg closure
-	b.WriteString(`</svg>`)
+// WriteBeam streams a beam-style avatar SVG to the given writer
+func WriteBeam(w io.Writer, name string, palette Palette, size int, square bool) error {
+	return write(w, Beam, name, palette, size, square)
+}
 
-	return b.String()
+// GenerateBeam returns a beam-style avatar SVG
+func GenerateBeam(name string, palette Palette, size int, square bool) string {
+	return generate(Beam, name, palette, size, square)
 }
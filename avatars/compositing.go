@@ -0,0 +1,36 @@
+package avatars
+
+import (
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// Background is implemented by generators whose content starts with a full-bleed background,
+// which transparent avatars leave out
+type Background interface {
+	// Background returns the number of leading drawn nodes that make up the background
+	Background() int
+}
+
+// compositeScene prepares the avatar for compositing into larger drawings:
+// it drops the mask and the style background, as requested.
+// It must run before the decorations, which wrap the masked group
+func compositeScene(s *scene.Scene, style Style, unmasked, transparent bool) {
+	group := s.Children[0].(*scene.Group)
+
+	if unmasked {
+		group.Mask = nil
+	}
+
+	if !transparent {
+		return
+	}
+
+	gen, ok := lookup(style)
+	if !ok {
+		gen = marbleGenerator
+	}
+
+	if b, ok := gen.(Background); ok {
+		group.Children = group.Children[min(b.Background(), len(group.Children)):]
+	}
+}
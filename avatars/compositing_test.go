@@ -0,0 +1,127 @@
+package avatars
+
+import (
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Compositing(t *testing.T) {
+	t.Parallel()
+
+	styles := []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset}
+
+	t.Run("unmasked", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range styles {
+			masked, err := Build(Options{Style: style, Name: "Grace"})
+			require.NoError(t, err)
+
+			s, err := Build(Options{Style: style, Name: "Grace", Unmasked: true})
+			require.NoError(t, err)
+
+			require.Len(t, s.Children, 1, style)
+
+			group := s.Children[0].(*scene.Group)
+
+			assert.Nil(t, group.Mask, style)
+			assert.Equal(t, masked.Children[0].(*scene.Group).Children, group.Children, style)
+
+			svg, err := Render(Options{Style: style, Name: "Grace", Unmasked: true})
+			require.NoError(t, err)
+
+			assert.NotContains(t, svg, "<mask", style)
+		}
+	})
+
+	t.Run("transparent", func(t *testing.T) {
+		t.Parallel()
+
+		testTable := []struct {
+			style   Style
+			dropped int
+		}{
+			{Beam, 1},
+			{Bauhaus, 1},
+			{Marble, 1},
+			{Pixel, 0},
+			{Ring, 2},
+			{Sunset, 0},
+		}
+
+		for _, testCase := range testTable {
+			full, err := Build(Options{Style: testCase.style, Name: "Grace"})
+			require.NoError(t, err)
+
+			s, err := Build(Options{Style: testCase.style, Name: "Grace", Transparent: true})
+			require.NoError(t, err)
+
+			var (
+				fullGroup = full.Children[0].(*scene.Group)
+				group     = s.Children[0].(*scene.Group)
+			)
+
+			// The mask is kept, unless dropped as well
+			assert.NotNil(t, group.Mask, testCase.style)
+			assert.Equal(t, fullGroup.Children[testCase.dropped:], group.Children, testCase.style)
+		}
+	})
+
+	t.Run("clean group", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Style: Beam, Name: "Grace", Unmasked: true, Transparent: true})
+		require.NoError(t, err)
+
+		assert.Contains(
+			t,
+			svg,
+			`xmlns="http://www.w3.org/2000/svg"><g><rect x="0" y="0" width="36" height="36" transform=`,
+		)
+	})
+
+	t.Run("animated", func(t *testing.T) {
+		t.Parallel()
+
+		// The animations keep the background in place, so it's still the one dropped
+		for _, style := range styles {
+			if style == Pixel {
+				continue // the twinkle adds overlay copies
+			}
+
+			animated, err := Build(Options{Style: style, Name: "Grace", Animate: true, Transparent: true})
+			require.NoError(t, err)
+
+			still, err := Build(Options{Style: style, Name: "Grace", Transparent: true})
+			require.NoError(t, err)
+
+			var count func(nodes []scene.Node) int
+
+			count = func(nodes []scene.Node) int {
+				n := 0
+
+				for _, node := range nodes {
+					if g, ok := node.(*scene.Group); ok && g.Animation != nil {
+						n += count(g.Children)
+
+						continue
+					}
+
+					n++
+				}
+
+				return n
+			}
+
+			assert.Equal(
+				t,
+				count(still.Children[0].(*scene.Group).Children),
+				count(animated.Children[0].(*scene.Group).Children),
+				style,
+			)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package avatars
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+var (
+	ErrInvalidBorder = errors.New("invalid border, expected a width in [0, 0.5] and a #RRGGBB color")
+	ErrInvalidBadge  = errors.New("invalid badge, expected a status or #RRGGBB color, a corner and a size in [0, 0.5]")
+)
+
+const (
+	// DefaultBorderWidth is the border width, relative to the avatar size, if none is set
+	DefaultBorderWidth = 0.05
+
+	// DefaultBorderColor is the border color, if none is set
+	DefaultBorderColor = white
+
+	// DefaultBadgeSize is the badge diameter, relative to the avatar size
+	DefaultBadgeSize = 0.25
+
+	// badgeGap is the width of the gap cut out around the badge, relative to the badge diameter
+	badgeGap = 0.15
+)
+
+// Border is an outline along the edge of the avatar, following its shape
+type Border struct {
+	// The border width, relative to the avatar size, such as 0.05 for 4px on an 80px avatar.
+	// Defaults to DefaultBorderWidth
+	Width float64
+
+	// The #RRGGBB border color. Defaults to DefaultBorderColor
+	Color string
+}
+
+// Status is the presence status shown by a badge
+type Status string
+
+const (
+	StatusOnline  Status = "online"
+	StatusAway    Status = "away"
+	StatusBusy    Status = "busy"
+	StatusOffline Status = "offline"
+)
+
+// statusColors are the badge colors of the presence statuses
+var statusColors = map[Status]string{
+	StatusOnline:  "#2ECC71",
+	StatusAway:    "#F1C40F",
+	StatusBusy:    "#E74C3C",
+	StatusOffline: "#95A5A6",
+}
+
+// Corner is the avatar corner a badge sits in
+type Corner string
+
+const (
+	BottomRight Corner = "bottom-right"
+	BottomLeft  Corner = "bottom-left"
+	TopRight    Corner = "top-right"
+	TopLeft     Corner = "top-left"
+)
+
+// Badge is a status dot in a corner of the avatar, set apart from it by a transparent gap
+type Badge struct {
+	// The presence status, which picks the badge color
+	Status Status
+
+	// The #RRGGBB badge color, which takes precedence over the status color
+	Color string
+
+	// The corner the badge sits in. Defaults to BottomRight
+	Corner Corner
+
+	// The badge diameter, relative to the avatar size. Defaults to DefaultBadgeSize
+	Size float64
+}
+
+// validate validates the border
+func (b Border) validate() error {
+	// Negated, so NaN widths fail the range check too
+	if !(b.Width >= 0 && b.Width <= 0.5) || b.Color != "" && !ValidColor(b.Color) {
+		return ErrInvalidBorder
+	}
+
+	return nil
+}
+
+// validate validates the badge
+func (b Badge) validate() error {
+	// The status is optional with a custom color, yet must be a known one if set
+	if _, ok := statusColors[b.Status]; !ok && (b.Status != "" || b.Color == "") {
+		return ErrInvalidBadge
+	}
+
+	if b.Color != "" && !ValidColor(b.Color) {
+		return ErrInvalidBadge
+	}
+
+	switch b.Corner {
+	case "", BottomRight, BottomLeft, TopRight, TopLeft:
+	default:
+		return ErrInvalidBadge
+	}
+
+	if !(b.Size >= 0 && b.Size <= 0.5) {
+		return ErrInvalidBadge
+	}
+
+	return nil
+}
+
+// decorateScene draws the border and badge on top of the avatar, in its viewBox units.
+// The badge gap is cut out of everything below it, border included
+func decorateScene(s *scene.Scene, style Style, seed int, shape outline, border *Border, badge *Badge) {
+	vb := s.ViewBox
+
+	if border != nil {
+		relative := border.Width
+		if relative == 0 {
+			relative = DefaultBorderWidth
+		}
+
+		var (
+			width = round2(relative * vb)
+			side  = round2(vb - width) // the stroke is centered on the outline
+			color = border.Color
+		)
+
+		if color == "" {
+			color = DefaultBorderColor
+		}
+
+		outline := shape.node(vb, round2(width/2), side)
+		*scene.AttrsOf(outline) = scene.Attrs{
+			Fill:        scene.None,
+			Stroke:      scene.Color(color),
+			StrokeWidth: width,
+		}
+
+		s.Children = append(s.Children, outline)
+	}
+
+	if badge == nil {
+		return
+	}
+
+	size := badge.Size
+	if size == 0 {
+		size = DefaultBadgeSize
+	}
+
+	color := badge.Color
+	if color == "" {
+		color = statusColors[badge.Status]
+	}
+
+	// The badge sits on the avatar edge, along the diagonal,
+	// unless the edge is so far out that the badge gets tucked into the corner
+	var (
+		r      = round2(size * vb / 2)
+		offset = round2(min(shape.corner(vb), vb-r)) // from the top left corner
+	)
+
+	cx, cy := offset, offset
+
+	switch badge.Corner {
+	case BottomLeft:
+		cx = round2(vb - offset)
+	case TopRight:
+		cy = round2(vb - offset)
+	case TopLeft:
+		cx, cy = round2(vb-offset), round2(vb-offset)
+	}
+
+	// Cut the gap out of the avatar, with a luminance mask
+	cutout := &scene.Mask{
+		ID: fmt.Sprintf("cutout_%s_%d", style, seed),
+		Children: []scene.Node{
+			&scene.Rect{
+				Width:  vb,
+				Height: vb,
+				Attrs: scene.Attrs{
+					Fill: scene.Color(white),
+				},
+			},
+			&scene.Circle{
+				CX: cx,
+				CY: cy,
+				R:  round2(r * (1 + 2*badgeGap)),
+				Attrs: scene.Attrs{
+					Fill: scene.Color(black),
+				},
+			},
+		},
+	}
+
+	s.Children = []scene.Node{
+		&scene.Group{
+			Mask:     cutout,
+			Children: s.Children,
+		},
+		&scene.Circle{
+			CX: cx,
+			CY: cy,
+			R:  r,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(color),
+			},
+		},
+	}
+}
+
+// round2 rounds the viewBox units to 2 decimals
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,128 @@
+package avatars
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Decorations(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid border", func(t *testing.T) {
+		t.Parallel()
+
+		for _, b := range []Border{{Width: -0.1}, {Width: 0.6}, {Width: math.NaN()}, {Width: 0.05, Color: "white"}} {
+			_, err := Render(Options{Name: "Grace", Border: &b})
+
+			assert.ErrorIs(t, err, ErrInvalidBorder, b)
+		}
+	})
+
+	t.Run("invalid badge", func(t *testing.T) {
+		t.Parallel()
+
+		for _, b := range []Badge{
+			{},
+			{Status: "asleep"},
+			{Status: "typo", Color: "#FF0000"},
+			{Color: "green"},
+			{Status: StatusOnline, Corner: "middle"},
+			{Status: StatusOnline, Size: 0.6},
+			{Status: StatusOnline, Size: math.NaN()},
+		} {
+			_, err := Render(Options{Name: "Grace", Badge: &b})
+
+			assert.ErrorIs(t, err, ErrInvalidBadge, b)
+		}
+	})
+
+	t.Run("round border", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Style: Beam, Name: "Grace", Border: &Border{}})
+		require.NoError(t, err)
+
+		// The default 0.05 of the 36-unit viewBox, in white
+		assert.Contains(
+			t,
+			svg,
+			`</g><rect x="0.9" y="0.9" width="34.2" height="34.2" rx="68.4" stroke-width="1.8" stroke="#FFFFFF"`+
+				` fill="none"/></svg>`,
+		)
+	})
+
+	t.Run("square border", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Name: "Grace", Square: true, Border: &Border{Width: 0.1, Color: "#222222"}})
+		require.NoError(t, err)
+
+		assert.Contains(t, svg, `<rect x="4" y="4" width="72" height="72" stroke-width="8" stroke="#222222" fill="none"/>`)
+	})
+
+	t.Run("badges", func(t *testing.T) {
+		t.Parallel()
+
+		testTable := []struct {
+			name     string
+			badge    Badge
+			square   bool
+			expected string
+		}{
+			{
+				"round bottom right",
+				Badge{Status: StatusOnline},
+				false,
+				`<circle cx="68.28" cy="68.28" r="10" fill="#2ECC71"/>`,
+			},
+			{
+				"round top left",
+				Badge{Status: StatusBusy, Corner: TopLeft, Size: 0.2},
+				false,
+				`<circle cx="11.72" cy="11.72" r="8" fill="#E74C3C"/>`,
+			},
+			{
+				"square top right",
+				Badge{Status: StatusAway, Corner: TopRight},
+				true,
+				`<circle cx="70" cy="10" r="10" fill="#F1C40F"/>`,
+			},
+			{
+				"square bottom left, custom color",
+				Badge{Status: StatusOffline, Color: "#123456", Corner: BottomLeft},
+				true,
+				`<circle cx="10" cy="70" r="10" fill="#123456"/>`,
+			},
+		}
+
+		for _, testCase := range testTable {
+			t.Run(testCase.name, func(t *testing.T) {
+				t.Parallel()
+
+				svg, err := Render(Options{Name: "Grace", Square: testCase.square, Badge: &testCase.badge})
+				require.NoError(t, err)
+
+				// The badge is drawn last, over the cut out avatar
+				assert.Contains(t, svg, `<g mask="url(#cutout_marble_`)
+				assert.Contains(t, svg, `</g>`+testCase.expected)
+			})
+		}
+	})
+
+	t.Run("unique cutout IDs", func(t *testing.T) {
+		t.Parallel()
+
+		render := func(corner Corner) string {
+			s, err := Build(Options{Name: "Grace", UniqueIDs: true, Badge: &Badge{Status: StatusOnline, Corner: corner}})
+			require.NoError(t, err)
+
+			return s.Children[0].(*scene.Group).Mask.ID
+		}
+
+		assert.NotEqual(t, render(TopLeft), render(BottomRight))
+	})
+}
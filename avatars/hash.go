@@ -0,0 +1,75 @@
+package avatars
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// maxSeed caps the wide hashes, so the style arithmetic on
+// the seed can't overflow into negative (invalid) values.
+// Seeds are 53-bit, or 31-bit on platforms with a 32-bit int
+const maxSeed = min(1<<53-1, math.MaxInt)
+
+// Hasher derives the avatar seed from the name.
+// Seeds are non-negative, and every style is derived from them
+type Hasher interface {
+	// Hash returns the seed for the name
+	Hash(name string) int
+}
+
+// JavaHasher is the 32-bit Java-style string hash (h*31+c), see NameToID.
+// It's the default hasher, matching the reference JS library.
+// Being 32-bit, it collides easily (e.g. "Aa" and "BB")
+type JavaHasher struct{}
+
+// Hash returns the Java-style seed for the name
+func (JavaHasher) Hash(name string) int {
+	return NameToID(name)
+}
+
+// FNV1aHasher is the 64-bit FNV-1a hash, truncated to the seed range (see maxSeed).
+// It's fast, and collides far less than the default hasher
+type FNV1aHasher struct{}
+
+// Hash returns the FNV-1a seed for the name
+func (FNV1aHasher) Hash(name string) int {
+	h := fnv.New64a()
+
+	_, _ = h.Write([]byte(name)) // writes to a hash never fail
+
+	return seed(h.Sum(nil))
+}
+
+// SHA256Hasher derives the seed from the SHA-256 digest of the name
+type SHA256Hasher struct{}
+
+// Hash returns the SHA-256 seed for the name
+func (SHA256Hasher) Hash(name string) int {
+	sum := sha256.Sum256([]byte(name))
+
+	return seed(sum[:])
+}
+
+// HMACHasher derives the seed from the keyed HMAC-SHA256 of the name,
+// so avatars can't be matched to names without knowing the key
+type HMACHasher struct {
+	// The secret HMAC key
+	Key []byte
+}
+
+// Hash returns the HMAC-SHA256 seed for the name
+func (h HMACHasher) Hash(name string) int {
+	mac := hmac.New(sha256.New, h.Key)
+
+	_, _ = mac.Write([]byte(name)) // writes to a hash never fail
+
+	return seed(mac.Sum(nil))
+}
+
+// seed converts the leading 8 bytes of the digest into a seed
+func seed(digest []byte) int {
+	return int(binary.BigEndian.Uint64(digest) & maxSeed)
+}
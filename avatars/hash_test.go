@@ -0,0 +1,115 @@
+package avatars
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasher(t *testing.T) {
+	t.Parallel()
+
+	hashers := map[string]Hasher{
+		"fnv1a":  FNV1aHasher{},
+		"sha256": SHA256Hasher{},
+		"hmac":   HMACHasher{Key: []byte("secret")},
+	}
+
+	t.Run("default hasher collisions", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, JavaHasher{}.Hash("Aa"), JavaHasher{}.Hash("BB"))
+
+		for name, h := range hashers {
+			assert.NotEqual(t, h.Hash("Aa"), h.Hash("BB"), name)
+		}
+	})
+
+	t.Run("seed range", func(t *testing.T) {
+		t.Parallel()
+
+		for name, h := range hashers {
+			for _, n := range []string{"", "Aa", "Amelia Earhart", "alice@example.com"} {
+				seed := h.Hash(n)
+
+				assert.GreaterOrEqual(t, seed, 0, name)
+				assert.LessOrEqual(t, seed, maxSeed, name)
+				assert.Equal(t, seed, h.Hash(n), name)
+			}
+		}
+	})
+
+	t.Run("wide seeds", func(t *testing.T) {
+		t.Parallel()
+
+		// Half the digests have their top bit set, which must never yield a negative seed
+		for i := range 1000 {
+			name := strconv.Itoa(i)
+
+			for hasherName, h := range hashers {
+				seed := h.Hash(name)
+
+				require.GreaterOrEqual(t, seed, 0, "%s %s", hasherName, name)
+				require.LessOrEqual(t, seed, maxSeed, "%s %s", hasherName, name)
+			}
+		}
+
+		_, err := Render(Options{Style: Beam, Hasher: SHA256Hasher{}})
+		require.NoError(t, err)
+	})
+
+	t.Run("keyed hasher", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotEqual(
+			t,
+			HMACHasher{Key: []byte("a")}.Hash("Amelia Earhart"),
+			HMACHasher{Key: []byte("b")}.Hash("Amelia Earhart"),
+		)
+	})
+
+	t.Run("rendered with every style", func(t *testing.T) {
+		t.Parallel()
+
+		for name, h := range hashers {
+			for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+				opts := Options{Style: style, Name: "Aa", Hasher: h}
+
+				aa, err := Render(opts)
+				require.NoError(t, err)
+
+				opts.Name = "BB"
+				bb, err := Render(opts)
+				require.NoError(t, err)
+
+				assert.NotEqual(t, aa, bb, "%s %s", name, style)
+			}
+		}
+	})
+
+	t.Run("default matches Generate", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Style: Beam, Name: "Amelia Earhart", Hasher: JavaHasher{}})
+		require.NoError(t, err)
+
+		assert.Equal(t, Generate(Beam, "Amelia Earhart", nil, 0, false), svg)
+	})
+}
+
+func TestHasher_MaxSeed(t *testing.T) {
+	t.Parallel()
+
+	// The style arithmetic must stay in range for the largest seeds,
+	// including 31-bit ones on platforms with a 32-bit int (GOARCH=386 in CI)
+	for _, id := range []int{maxSeed, maxSeed - 1, maxSeed / 3, math.MaxInt32} {
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			assert.NotPanics(t, func() {
+				build(style, id, nil, 0, squareOutline(false))
+			}, "%s %d", style, id)
+		}
+	}
+}
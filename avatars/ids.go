@@ -0,0 +1,70 @@
+package avatars
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidIDPrefix = errors.New("invalid ID prefix, expected a letter or _, followed by letters, digits, _, - or .")
+
+// idPrefixRegex matches ID prefixes that keep the SVG IDs valid XML names
+var idPrefixRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ValidIDPrefix checks if the ID prefix keeps the avatar IDs valid
+func ValidIDPrefix(prefix string) bool {
+	return idPrefixRegex.MatchString(prefix)
+}
+
+// idPrefix returns the full prefix of the avatar IDs: the explicit prefix,
+// followed by the one derived from the render params, if UniqueIDs is set
+func (o Options) idPrefix() string {
+	if !o.UniqueIDs {
+		return o.IDPrefix
+	}
+
+	palette := o.Palette
+	if len(palette) == 0 {
+		palette = DefaultPalette
+	}
+
+	style := o.Style
+	if !ValidStyle(style) {
+		style = Marble
+	}
+
+	params := []string{
+		string(style),
+		strconv.Itoa(o.Seed()),
+		strings.Join(palette, ","),
+		strconv.Itoa(o.Size),
+		strconv.FormatBool(o.Square),
+	}
+
+	// Shapes other than the square flag ones shape the mask
+	if shape := o.outline(); shape != squareOutline(o.Square) {
+		params = append(params, "shape", string(shape.shape), strconv.FormatFloat(shape.radius, 'g', -1, 64))
+	}
+
+	// The dark palette recolors the gradient stops, so it's part of the params
+	if len(o.DarkPalette) > 0 {
+		params = append(params, strings.Join(o.DarkPalette, ","))
+	}
+
+	// So does the badge, which shapes the cutout mask
+	if b := o.Badge; b != nil {
+		params = append(params, "badge", string(b.Corner), strconv.FormatFloat(b.Size, 'g', -1, 64))
+	}
+
+	// The label IDs only derive from the seed, so the labels are part of the params too
+	if a := o.Accessibility; a != nil {
+		params = append(params, "a11y", a.title(o.Name), a.Desc)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(params, "\x00")))
+
+	return o.IDPrefix + "a" + hex.EncodeToString(sum[:6]) + "_"
+}
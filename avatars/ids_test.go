@@ -0,0 +1,94 @@
+package avatars
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var idRegex = regexp.MustCompile(`id="([^"]+)"`)
+
+// ids returns the element IDs of the rendered avatar
+func ids(t *testing.T, opts Options) []string {
+	t.Helper()
+
+	svg, err := Render(opts)
+	require.NoError(t, err)
+
+	var out []string
+
+	for _, m := range idRegex.FindAllStringSubmatch(svg, -1) {
+		out = append(out, m[1])
+	}
+
+	require.NotEmpty(t, out)
+
+	return out
+}
+
+func TestOptions_IDPrefix(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid prefix", func(t *testing.T) {
+		t.Parallel()
+
+		for _, prefix := range []string{"1a", "a b", `a"`, "-a"} {
+			_, err := Render(Options{Name: "Grace", IDPrefix: prefix})
+
+			assert.ErrorIs(t, err, ErrInvalidIDPrefix, prefix)
+		}
+	})
+
+	t.Run("unique IDs", func(t *testing.T) {
+		t.Parallel()
+
+		opts := Options{Style: Marble, Name: "Grace", UniqueIDs: true}
+
+		// The same avatar always gets the same IDs
+		assert.Equal(t, ids(t, opts), ids(t, opts))
+
+		// Any param change makes them unique
+		base := ids(t, opts)
+
+		for _, change := range []func(o *Options){
+			func(o *Options) { o.Palette = Palette{"#000000", "#FFFFFF"} },
+			func(o *Options) { o.Size = 40 },
+			func(o *Options) { o.Square = true },
+			func(o *Options) { o.Accessibility = &Accessibility{Desc: "A marble avatar"} },
+		} {
+			changed := opts
+			change(&changed)
+
+			for _, id := range ids(t, changed) {
+				assert.NotContains(t, base, id)
+			}
+		}
+	})
+
+	t.Run("same seed labels", func(t *testing.T) {
+		t.Parallel()
+
+		// "Aa" and "BB" share a seed, yet their titles differ
+		aa := Options{Style: Marble, Name: "Aa", UniqueIDs: true, Accessibility: &Accessibility{}}
+		bb := aa
+		bb.Name = "BB"
+
+		assert.NotEqual(t, aa.idPrefix(), bb.idPrefix())
+
+		// Hidden names share the title, and so the IDs
+		aa.Accessibility = &Accessibility{HideName: true}
+		bb.Accessibility = &Accessibility{HideName: true}
+
+		assert.Equal(t, aa.idPrefix(), bb.idPrefix())
+	})
+
+	t.Run("explicit and unique prefix", func(t *testing.T) {
+		t.Parallel()
+
+		for _, id := range ids(t, Options{Style: Sunset, Name: "Grace", IDPrefix: "page1_", UniqueIDs: true}) {
+			assert.Regexp(t, `^page1_a[0-9a-f]{12}_`, id)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package avatars
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+const (
+	// initialsFontFamily is the font family of the initials overlay
+	initialsFontFamily = "Helvetica, Arial, sans-serif"
+
+	// initialsScale is the initials font size, relative to the viewBox
+	initialsScale = 0.4
+)
+
+// Backdrop is an optional Generator extension, reporting the color
+// the initials overlay is drawn on, at the center of the avatar.
+// The initials of generators without it contrast with the first palette color
+type Backdrop interface {
+	// Backdrop returns the (#RRGGBB) color at the center of the avatar
+	Backdrop(seed int, palette Palette) string
+}
+
+// Initials extracts up to two initials from the name: the first letters (or digits)
+// of its first and last words. Emails only use their local part, without the +tag,
+// which is also split into words on dots, dashes and underscores.
+// The initials keep their combining marks, and are uppercased
+func Initials(name string) string {
+	name = strings.TrimSpace(name)
+
+	separator := unicode.IsSpace
+
+	if at := strings.LastIndexByte(name, '@'); at > 0 && !strings.ContainsFunc(name, unicode.IsSpace) {
+		name, _, _ = strings.Cut(name[:at], "+")
+
+		separator = func(r rune) bool {
+			return r == '.' || r == '-' || r == '_'
+		}
+	}
+
+	var initials []string
+
+	for _, word := range strings.FieldsFunc(name, separator) {
+		if i := initial(word); i != "" {
+			initials = append(initials, i)
+		}
+	}
+
+	switch len(initials) {
+	case 0:
+		return ""
+	case 1:
+		return strings.ToUpper(initials[0])
+	default:
+		return strings.ToUpper(initials[0] + initials[len(initials)-1])
+	}
+}
+
+// initial returns the first letter (or digit) of the word,
+// along with the combining marks that follow it
+func initial(word string) string {
+	for i, r := range word {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+
+		end := i + utf8.RuneLen(r)
+
+		for _, m := range word[end:] {
+			if !unicode.Is(unicode.M, m) {
+				break
+			}
+
+			end += utf8.RuneLen(m)
+		}
+
+		return word[i:end]
+	}
+
+	return ""
+}
+
+// addInitials draws the name's initials on top of the avatar content, in the color
+// contrasting the most with the style's backdrop (in both palettes, if there's a dark one).
+// The initials are centered, and scaled to the style's viewBox
+func addInitials(s *scene.Scene, style Style, seed int, palette, dark Palette, name string) {
+	text := Initials(name)
+	if text == "" {
+		return
+	}
+
+	group, ok := s.Children[0].(*scene.Group) // the masked avatar content
+	if !ok {
+		return
+	}
+
+	gen, ok := lookup(style)
+	if !ok {
+		gen = marbleGenerator
+	}
+
+	if len(palette) == 0 {
+		palette = DefaultPalette
+	}
+
+	contrast := func(palette Palette) string {
+		backdrop := palette[0]
+		if b, ok := gen.(Backdrop); ok {
+			backdrop = b.Backdrop(seed, palette)
+		}
+
+		return Contrast(backdrop)
+	}
+
+	fill := scene.Color(contrast(palette))
+	if len(dark) > 0 {
+		if c := contrast(dark); c != fill.Color {
+			fill.Dark = c
+		}
+	}
+
+	center := float64(gen.ViewBox()) / 2
+
+	group.Children = append(group.Children, &scene.Text{
+		X:          center,
+		Y:          center,
+		FontFamily: initialsFontFamily,
+		FontSize:   float64(gen.ViewBox()) * initialsScale,
+		FontWeight: 600,
+		Content:    text,
+		Attrs: scene.Attrs{
+			Fill: fill,
+		},
+	})
+}
+
+// mixColors returns the average of the two (#RRGGBB) colors
+func mixColors(a, b string) string {
+	channel := func(c string, i int) int64 {
+		v, _ := strconv.ParseInt(c[1+2*i:3+2*i], 16, 64)
+
+		return v
+	}
+
+	if !ValidColor(a) || !ValidColor(b) {
+		return a
+	}
+
+	return fmt.Sprintf(
+		"#%02X%02X%02X",
+		(channel(a, 0)+channel(b, 0))/2,
+		(channel(a, 1)+channel(b, 1))/2,
+		(channel(a, 2)+channel(b, 2))/2,
+	)
+}
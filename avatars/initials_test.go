@@ -0,0 +1,99 @@
+package avatars
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitials(t *testing.T) {
+	t.Parallel()
+
+	testTable := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"blank", "  \t ", ""},
+		{"single word", "grace", "G"},
+		{"two words", "Grace Hopper", "GH"},
+		{"first and last word", "Grace Brewster Murray Hopper", "GH"},
+		{"surrounding spaces", "  ada   lovelace ", "AL"},
+		{"hyphenated", "Jean-Luc Picard", "JP"},
+		{"punctuation", "(Grace) 'Hopper'", "GH"},
+		{"digits", "42 wallaby", "4W"},
+		{"email", "grace.hopper@example.com", "GH"},
+		{"email dashes", "ada_king-lovelace@example.com", "AL"},
+		{"email tag", "grace+avatars@example.com", "G"},
+		{"not an email", "Grace @ Navy", "GN"},
+		{"greek", "ωμέγα ἄλφα", "ΩἌ"},
+		{"cyrillic", "лев толстой", "ЛТ"},
+		{"cjk", "山田 太郎", "山太"},
+		{"combining marks", "émile zola", "ÉZ"},
+		{"no letters", "!!! ???", ""},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, Initials(testCase.input))
+		})
+	}
+}
+
+func TestOptions_Initials(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Name: "Grace Hopper"})
+		require.NoError(t, err)
+
+		assert.NotContains(t, svg, "<text")
+	})
+
+	t.Run("every style", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range Styles() {
+			gen, ok := lookup(style)
+			require.True(t, ok)
+
+			svg, err := Render(Options{Style: style, Name: "Grace Hopper", Initials: true})
+			require.NoError(t, err)
+
+			center := strconv.Itoa(gen.ViewBox() / 2)
+
+			// The initials are drawn last, inside the masked content
+			assert.Contains(t, svg, `<text x="`+center+`" y="`+center+`"`, style)
+			assert.Contains(t, svg, ">GH</text></g>", style)
+			assert.Equal(t, 1, strings.Count(svg, "<text"), style)
+		}
+	})
+
+	t.Run("contrasting color", func(t *testing.T) {
+		t.Parallel()
+
+		for _, palette := range []Palette{{"#000000"}, {"#FFFFFF"}} {
+			svg, err := Render(Options{Style: Bauhaus, Name: "Grace Hopper", Palette: palette, Initials: true})
+			require.NoError(t, err)
+
+			assert.Contains(t, svg, `fill="`+Contrast(palette[0])+`">GH</text>`)
+		}
+	})
+
+	t.Run("no initials", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{Name: "???", Initials: true})
+		require.NoError(t, err)
+
+		assert.NotContains(t, svg, "<text")
+	})
+}
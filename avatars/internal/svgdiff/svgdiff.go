@@ -0,0 +1,337 @@
+// Package svgdiff compares SVG documents structurally, ignoring the
+// differences that don't change the rendered result: attribute order,
+// number formatting, color case, generated IDs, explicit initial values,
+// titles and whitespace
+package svgdiff
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Element is a normalized SVG element
+type Element struct {
+	Attrs    map[string]string
+	Name     string
+	Children []*Element
+}
+
+// Difference is a single difference between two documents
+type Difference struct {
+	// The element path, such as svg/g/rect[1]
+	Path string
+
+	// The differing attribute, if any.
+	// Empty for structural (element) differences
+	Attr string
+
+	// The expected and actual values
+	Want, Got string
+}
+
+// String returns the human-readable difference
+func (d Difference) String() string {
+	if d.Attr == "" {
+		return fmt.Sprintf("%s: want %s, got %s", d.Path, d.Want, d.Got)
+	}
+
+	return fmt.Sprintf("%s@%s: want %q, got %q", d.Path, d.Attr, d.Want, d.Got)
+}
+
+// Parse parses and normalizes the SVG document
+func Parse(r io.Reader) (*Element, error) {
+	var (
+		d     = xml.NewDecoder(r)
+		stack []*Element
+		root  *Element
+	)
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse SVG, %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &Element{
+				Name:  t.Name.Local,
+				Attrs: make(map[string]string, len(t.Attr)),
+			}
+
+			for _, a := range t.Attr {
+				// Namespace declarations are not part of the drawing
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+					continue
+				}
+
+				e.Attrs[a.Name.Local] = a.Value
+			}
+
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("multiple root elements")
+				}
+
+				root = e
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			}
+
+			stack = append(stack, e)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("empty SVG document")
+	}
+
+	normalize(root)
+
+	return root, nil
+}
+
+// ParseString parses and normalizes the SVG document
+func ParseString(s string) (*Element, error) {
+	return Parse(strings.NewReader(s))
+}
+
+var (
+	numberRe = regexp.MustCompile(`[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+	urlRe    = regexp.MustCompile(`url\(\s*#([^)\s]+)\s*\)`)
+)
+
+// normalize normalizes the document in place
+func normalize(root *Element) {
+	dropTitles(root)
+
+	// Canonical IDs, in document order
+	ids := make(map[string]string)
+
+	walk(root, func(e *Element) {
+		if id, ok := e.Attrs["id"]; ok {
+			ids[id] = "id" + strconv.Itoa(len(ids))
+		}
+	})
+
+	walk(root, func(e *Element) {
+		for name, v := range e.Attrs {
+			e.Attrs[name] = normalizeValue(name, v, ids)
+
+			// Explicit initial values are the same as omitted ones
+			if zeroDefaults[e.Name][name] && e.Attrs[name] == "0" {
+				delete(e.Attrs, name)
+			}
+		}
+	})
+}
+
+// zeroDefaults are the element attributes with an initial value of 0
+var zeroDefaults = map[string]map[string]bool{
+	"rect":   {"x": true, "y": true},
+	"circle": {"cx": true, "cy": true},
+	"line":   {"x1": true, "y1": true, "x2": true, "y2": true},
+	"stop":   {"offset": true},
+}
+
+// dropTitles removes the accessibility text elements
+func dropTitles(e *Element) {
+	e.Children = slices.DeleteFunc(e.Children, func(c *Element) bool {
+		return c.Name == "title" || c.Name == "desc"
+	})
+
+	for _, c := range e.Children {
+		dropTitles(c)
+	}
+}
+
+// normalizeValue normalizes a single attribute value
+func normalizeValue(name, v string, ids map[string]string) string {
+	v = strings.TrimSpace(v)
+
+	switch {
+	case name == "id":
+		return ids[v]
+	case name == "style":
+		return normalizeStyle(v)
+	case strings.HasPrefix(v, "#"):
+		if id, ok := ids[v[1:]]; ok && (name == "href" || name == "mask") {
+			return "#" + id
+		}
+
+		return strings.ToUpper(v)
+	case strings.Contains(v, "url("):
+		return urlRe.ReplaceAllStringFunc(v, func(ref string) string {
+			if id, ok := ids[urlRe.FindStringSubmatch(ref)[1]]; ok {
+				return "url(#" + id + ")"
+			}
+
+			return ref
+		})
+	default:
+		return normalizeNumbers(v)
+	}
+}
+
+// normalizeNumbers rewrites the numbers in the value in their shortest form,
+// rounded to 4 decimals (6.00 -> 6, 1.30 -> 1.3), and collapses whitespace
+func normalizeNumbers(v string) string {
+	v = numberRe.ReplaceAllStringFunc(v, func(s string) string {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return s
+		}
+
+		n, _ = strconv.ParseFloat(strconv.FormatFloat(n, 'f', 4, 64), 64)
+		if n == 0 {
+			n = 0 // no negative zero
+		}
+
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	})
+
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// normalizeStyle sorts the style declarations, and normalizes their spacing
+func normalizeStyle(v string) string {
+	var decls []string
+
+	for _, d := range strings.Split(v, ";") {
+		prop, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+
+		decls = append(decls, strings.ToLower(strings.TrimSpace(prop))+":"+strings.TrimSpace(value))
+	}
+
+	slices.Sort(decls)
+
+	return strings.Join(decls, ";")
+}
+
+// walk calls fn for every element, depth first
+func walk(e *Element, fn func(e *Element)) {
+	fn(e)
+
+	for _, c := range e.Children {
+		walk(c, fn)
+	}
+}
+
+// Diff compares the normalized documents, returning all differences
+// in document order (empty if the documents are equivalent)
+func Diff(want, got *Element) []Difference {
+	var out []Difference
+
+	diff(want, got, want.Name, &out)
+
+	return out
+}
+
+// Compare parses and compares the two SVG documents
+func Compare(want, got string) ([]Difference, error) {
+	w, err := ParseString(want)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected SVG, %w", err)
+	}
+
+	g, err := ParseString(got)
+	if err != nil {
+		return nil, fmt.Errorf("invalid actual SVG, %w", err)
+	}
+
+	return Diff(w, g), nil
+}
+
+func diff(want, got *Element, path string, out *[]Difference) {
+	if want.Name != got.Name {
+		*out = append(*out, Difference{
+			Path: path,
+			Want: "<" + want.Name + ">",
+			Got:  "<" + got.Name + ">",
+		})
+
+		return
+	}
+
+	// Attributes, in name order
+	names := make([]string, 0, len(want.Attrs)+len(got.Attrs))
+
+	for name := range want.Attrs {
+		names = append(names, name)
+	}
+
+	for name := range got.Attrs {
+		if _, ok := want.Attrs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		w, wok := want.Attrs[name]
+		g, gok := got.Attrs[name]
+
+		switch {
+		case !wok:
+			w = "(missing)"
+		case !gok:
+			g = "(missing)"
+		}
+
+		if w != g || wok != gok {
+			*out = append(*out, Difference{Path: path, Attr: name, Want: w, Got: g})
+		}
+	}
+
+	// Children, pairwise
+	counts := make(map[string]int)
+
+	for i := range max(len(want.Children), len(got.Children)) {
+		switch {
+		case i >= len(got.Children):
+			*out = append(*out, Difference{
+				Path: childPath(path, want.Children[i].Name, counts),
+				Want: "<" + want.Children[i].Name + ">",
+				Got:  "(missing)",
+			})
+		case i >= len(want.Children):
+			*out = append(*out, Difference{
+				Path: childPath(path, got.Children[i].Name, counts),
+				Want: "(missing)",
+				Got:  "<" + got.Children[i].Name + ">",
+			})
+		default:
+			diff(want.Children[i], got.Children[i], childPath(path, want.Children[i].Name, counts), out)
+		}
+	}
+}
+
+// childPath returns the path of the next child with the given name,
+// indexed among its same-name siblings
+func childPath(parent, name string, counts map[string]int) string {
+	i := counts[name]
+	counts[name]++
+
+	if i == 0 {
+		return parent + "/" + name
+	}
+
+	return fmt.Sprintf("%s/%s[%d]", parent, name, i)
+}
@@ -0,0 +1,62 @@
+package svgdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	t.Run("equivalent documents", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			want = `<svg viewBox="0 0 36 36" xmlns="http://www.w3.org/2000/svg"><title>Mary</title>` +
+				`<mask id="mask__beam"><rect width="36" height="36" fill="#ffffff"/></mask>` +
+				`<g mask="url(#mask__beam)">` +
+				`<rect x="0" y="0" transform="translate(6 6) rotate(324 18 18) scale(1.3)" fill="#fb8500"/>` +
+				`<path style="mix-blend-mode: overlay;" d="M15 19c2 1 4 1 6 0"/></g></svg>`
+			got = `<svg viewBox="0 0 36 36"><mask id="mask_beam_42"><rect fill="#FFFFFF" width="36" height="36"/></mask>` +
+				`<g mask="url(#mask_beam_42)">` +
+				`<rect fill="#FB8500" transform="translate(6.00 6.00) rotate(324 18 18) scale(1.30)"/>` +
+				`<path d="M15 19c2 1 4 1 6 0" style="mix-blend-mode:overlay"/></g></svg>`
+		)
+
+		diffs, err := Compare(want, got)
+		require.NoError(t, err)
+
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("reported differences", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			want = `<svg><g><rect fill="#000000"/><rect rx="2"/><circle r="1"/></g></svg>`
+			got  = `<svg><g><rect fill="#000000"/><rect rx="3" x="1"/></g><line/></svg>`
+		)
+
+		diffs, err := Compare(want, got)
+		require.NoError(t, err)
+
+		assert.Equal(t, []Difference{
+			{Path: "svg/g/rect[1]", Attr: "rx", Want: "2", Got: "3"},
+			{Path: "svg/g/rect[1]", Attr: "x", Want: "(missing)", Got: "1"},
+			{Path: "svg/g/circle", Want: "<circle>", Got: "(missing)"},
+			{Path: "svg/line", Want: "(missing)", Got: "<line>"},
+		}, diffs)
+
+		assert.Equal(t, `svg/g/rect[1]@rx: want "2", got "3"`, diffs[0].String())
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Compare("<svg>", "<svg/>")
+
+		assert.Error(t, err)
+	})
+}
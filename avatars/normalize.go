@@ -0,0 +1,151 @@
+package avatars
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrUnknownNormalization = errors.New("unknown normalization")
+
+// Normalization is a set of name normalization steps, applied before the name is hashed,
+// so different spellings of the same identity map to the same avatar
+type Normalization uint8
+
+const (
+	// NormalizeTrim trims the surrounding whitespace
+	NormalizeTrim Normalization = 1 << iota
+
+	// NormalizeNFC applies the Unicode canonical composition (NFC),
+	// so precomposed and combining character spellings match
+	NormalizeNFC
+
+	// NormalizeNFKC applies the Unicode compatibility composition (NFKC),
+	// which additionally folds compatibility characters (such as fullwidth letters).
+	// It supersedes NormalizeNFC
+	NormalizeNFKC
+
+	// NormalizeCaseFold applies Unicode case folding
+	NormalizeCaseFold
+
+	// NormalizeEmail canonicalizes email addresses, stripping the +tag
+	// from the local part and lowercasing the domain.
+	// Names that are not email addresses are left as-is
+	NormalizeEmail
+
+	// NormalizeNone leaves the name as-is
+	NormalizeNone Normalization = 0
+
+	// NormalizeIdentity applies every step, for names that are user identities
+	// (usernames and emails)
+	NormalizeIdentity = NormalizeTrim | NormalizeNFKC | NormalizeCaseFold | NormalizeEmail
+)
+
+// normalizationNames are the normalization step names, in application order
+var normalizationNames = []struct {
+	name string
+	step Normalization
+}{
+	{"nfc", NormalizeNFC},
+	{"nfkc", NormalizeNFKC},
+	{"trim", NormalizeTrim},
+	{"casefold", NormalizeCaseFold},
+	{"email", NormalizeEmail},
+}
+
+// ParseNormalization parses a comma separated list of normalization steps
+// (trim, nfc, nfkc, casefold and email), or "identity" for all of them
+func ParseNormalization(s string) (Normalization, error) {
+	var n Normalization
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+
+		switch part {
+		case "", "none":
+			continue
+		case "identity":
+			n |= NormalizeIdentity
+
+			continue
+		}
+
+		found := false
+
+		for _, s := range normalizationNames {
+			if s.name == part {
+				n |= s.step
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("%w: %q", ErrUnknownNormalization, part)
+		}
+	}
+
+	return n, nil
+}
+
+// String returns the comma separated list of normalization steps
+func (n Normalization) String() string {
+	parts := make([]string, 0, len(normalizationNames))
+
+	for _, s := range normalizationNames {
+		if n&s.step != 0 {
+			parts = append(parts, s.name)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "none"
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Normalize applies the normalization steps to the name.
+// Unicode composition goes first, followed by trimming, case folding
+// and finally, email canonicalization
+func Normalize(name string, n Normalization) string {
+	switch {
+	case n&NormalizeNFKC != 0:
+		name = norm.NFKC.String(name)
+	case n&NormalizeNFC != 0:
+		name = norm.NFC.String(name)
+	}
+
+	if n&NormalizeTrim != 0 {
+		name = strings.TrimSpace(name)
+	}
+
+	if n&NormalizeCaseFold != 0 {
+		name = cases.Fold().String(name)
+	}
+
+	if n&NormalizeEmail != 0 {
+		name = canonicalEmail(name)
+	}
+
+	return name
+}
+
+// canonicalEmail strips the +tag from the email local part,
+// and lowercases the domain. Anything else is returned as-is
+func canonicalEmail(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return s
+	}
+
+	if tagless, _, tagged := strings.Cut(local, "+"); tagged && tagless != "" {
+		local = tagless
+	}
+
+	return local + "@" + strings.ToLower(domain)
+}
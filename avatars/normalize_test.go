@@ -0,0 +1,76 @@
+package avatars
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("steps", func(t *testing.T) {
+		t.Parallel()
+
+		testTable := []struct {
+			name, input, expected string
+			n                     Normalization
+		}{
+			{"none", " Alice ", " Alice ", NormalizeNone},
+			{"trim", " \tAlice\n", "Alice", NormalizeTrim},
+			{"nfc", "Zoe\u0308", "Zo\u00eb", NormalizeNFC},
+			{"nfkc", "Ａlice", "Alice", NormalizeNFKC},
+			{"casefold", "STRASSE Ärger", "strasse ärger", NormalizeCaseFold},
+			{"email tag", "alice+news@Example.COM", "alice@example.com", NormalizeEmail},
+			{"email case kept", "Alice@example.com", "Alice@example.com", NormalizeEmail},
+			{"not an email", "@alice+bob", "@alice+bob", NormalizeEmail},
+			{"identity", " Alice+Work@Example.com ", "alice@example.com", NormalizeIdentity},
+		}
+
+		for _, testCase := range testTable {
+			t.Run(testCase.name, func(t *testing.T) {
+				t.Parallel()
+
+				assert.Equal(t, testCase.expected, Normalize(testCase.input, testCase.n))
+			})
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		t.Parallel()
+
+		n, err := ParseNormalization(" Trim, casefold ,email")
+		require.NoError(t, err)
+
+		assert.Equal(t, NormalizeTrim|NormalizeCaseFold|NormalizeEmail, n)
+		assert.Equal(t, "trim,casefold,email", n.String())
+
+		n, err = ParseNormalization("identity")
+		require.NoError(t, err)
+
+		assert.Equal(t, NormalizeIdentity, n)
+
+		n, err = ParseNormalization("")
+		require.NoError(t, err)
+
+		assert.Equal(t, NormalizeNone, n)
+		assert.Equal(t, "none", n.String())
+
+		_, err = ParseNormalization("trim,lowercase")
+		assert.ErrorIs(t, err, ErrUnknownNormalization)
+	})
+
+	t.Run("same identity, same avatar", func(t *testing.T) {
+		t.Parallel()
+
+		a, err := Render(Options{Name: "Alice@Example.com", Normalize: NormalizeIdentity})
+		require.NoError(t, err)
+
+		b, err := Render(Options{Name: "alice@example.com ", Normalize: NormalizeIdentity})
+		require.NoError(t, err)
+
+		assert.Equal(t, a, b)
+		assert.Equal(t, Generate(Marble, "alice@example.com", nil, 0, false), a)
+	})
+}
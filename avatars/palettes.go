@@ -0,0 +1,145 @@
+package avatars
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownPalette = errors.New("unknown palette")
+	ErrInvalidPalette = errors.New("invalid palette")
+)
+
+// catalog holds the named palettes
+var catalog = struct {
+	sync.RWMutex
+
+	palettes map[string]Palette
+}{
+	palettes: map[string]Palette{
+		// The default palette of this library
+		"default": DefaultPalette,
+
+		// The default palette of the upstream project
+		"boring": {"#92A1C6", "#146A7C", "#F0AB3D", "#C271B4", "#C20D90"},
+
+		// The palette showcased in the upstream project's README
+		"neon": {"#0A0310", "#49007E", "#FF005B", "#FF7D10", "#FFB238"},
+
+		// The top palettes of the nice-color-palettes collection
+		"nice-1":  {"#69D2E7", "#A7DBD8", "#E0E4CC", "#F38630", "#FA6900"},
+		"nice-2":  {"#FE4365", "#FC9D9A", "#F9CDAD", "#C8C8A9", "#83AF9B"},
+		"nice-3":  {"#ECD078", "#D95B43", "#C02942", "#542437", "#53777A"},
+		"nice-4":  {"#556270", "#4ECDC4", "#C7F464", "#FF6B6B", "#C44D58"},
+		"nice-5":  {"#774F38", "#E08E79", "#F1D4AF", "#ECE5CE", "#C5E0DC"},
+		"nice-6":  {"#E8DDCB", "#CDB380", "#036564", "#033649", "#031634"},
+		"nice-7":  {"#490A3D", "#BD1550", "#E97F02", "#F8CA00", "#8A9B0F"},
+		"nice-8":  {"#594F4F", "#547980", "#45ADA8", "#9DE0AD", "#E5FCC2"},
+		"nice-9":  {"#00A0B0", "#6A4A3C", "#CC333F", "#EB6841", "#EDC951"},
+		"nice-10": {"#E94E77", "#D68189", "#C6A49A", "#C6E5D9", "#F4EAD5"},
+	},
+}
+
+// ValidatePalette validates the palette, which needs at least one #RRGGBB color
+func ValidatePalette(palette Palette) error {
+	if len(palette) == 0 {
+		return fmt.Errorf("%w: no colors", ErrInvalidPalette)
+	}
+
+	for _, c := range palette {
+		if !ValidColor(c) {
+			return fmt.Errorf("%w: %q", ErrInvalidColor, c)
+		}
+	}
+
+	return nil
+}
+
+// RegisterPalette adds the named palette to the catalog.
+// Palette names are case-insensitive, and can't be registered twice
+func RegisterPalette(name string, palette Palette) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidPalette)
+	}
+
+	if err := ValidatePalette(palette); err != nil {
+		return err
+	}
+
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	if _, exists := catalog.palettes[name]; exists {
+		return fmt.Errorf("%w: %q is already registered", ErrInvalidPalette, name)
+	}
+
+	catalog.palettes[name] = slices.Clone(palette)
+
+	return nil
+}
+
+// unregisterPalette removes the named palette from the catalog, so tests can clean up after RegisterPalette
+func unregisterPalette(name string) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	delete(catalog.palettes, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// LookupPalette returns a copy of the named palette, if it's in the catalog
+func LookupPalette(name string) (Palette, bool) {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	palette, ok := catalog.palettes[strings.ToLower(strings.TrimSpace(name))]
+
+	return slices.Clone(palette), ok
+}
+
+// Palettes returns the names of the palettes in the catalog, sorted
+func Palettes() []string {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	names := make([]string, 0, len(catalog.palettes))
+
+	for name := range catalog.palettes {
+		names = append(names, name)
+	}
+
+	slices.SortFunc(names, comparePaletteNames)
+
+	return names
+}
+
+// comparePaletteNames orders the names alphabetically,
+// with numbered suffixes in numeric order (nice-2 before nice-10)
+func comparePaletteNames(a, b string) int {
+	ap, an := splitNumber(a)
+	bp, bn := splitNumber(b)
+
+	if c := strings.Compare(ap, bp); c != 0 {
+		return c
+	}
+
+	return an - bn
+}
+
+// splitNumber splits the trailing number off the name, if any
+func splitNumber(name string) (string, int) {
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+
+	n := 0
+	for _, c := range name[i:] {
+		n = n*10 + int(c-'0')
+	}
+
+	return name[:i], n
+}
@@ -0,0 +1,75 @@
+package avatars
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPalettes(t *testing.T) {
+	t.Parallel()
+
+	t.Run("catalog", func(t *testing.T) {
+		t.Parallel()
+
+		names := Palettes()
+
+		assert.Subset(t, names, []string{"default", "boring", "neon", "nice-1", "nice-10"})
+		assert.Less(t, indexOf(names, "nice-2"), indexOf(names, "nice-10"))
+
+		for _, name := range names {
+			palette, ok := LookupPalette(name)
+			require.True(t, ok, name)
+
+			assert.NoError(t, ValidatePalette(palette), name)
+		}
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		t.Parallel()
+
+		palette, ok := LookupPalette(" Default ")
+		require.True(t, ok)
+
+		assert.Equal(t, DefaultPalette, palette)
+
+		// Lookups return copies
+		palette[0] = "#000000"
+
+		assert.NotEqual(t, "#000000", DefaultPalette[0])
+
+		_, ok = LookupPalette("missing")
+		assert.False(t, ok)
+	})
+
+	// Not parallel, so the other subtests never see the registered palette
+	t.Run("register", func(t *testing.T) {
+		require.NoError(t, RegisterPalette("Test-Brand", Palette{"#123456", "#abcdef"}))
+		t.Cleanup(func() {
+			unregisterPalette("test-brand")
+		})
+
+		palette, ok := LookupPalette("test-brand")
+		require.True(t, ok)
+
+		assert.Equal(t, Palette{"#123456", "#abcdef"}, palette)
+		assert.Contains(t, Palettes(), "test-brand")
+
+		assert.ErrorIs(t, RegisterPalette("test-brand", Palette{"#123456"}), ErrInvalidPalette)
+		assert.ErrorIs(t, RegisterPalette("", Palette{"#123456"}), ErrInvalidPalette)
+		assert.ErrorIs(t, RegisterPalette("test-empty", nil), ErrInvalidPalette)
+		assert.ErrorIs(t, RegisterPalette("test-red", Palette{"red"}), ErrInvalidColor)
+	})
+}
+
+// indexOf returns the index of the value in the list, or -1
+func indexOf(list []string, v string) int {
+	for i, item := range list {
+		if item == v {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,176 @@
+package avatars
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/avatars/internal/svgdiff"
+	"github.com/stretchr/testify/require"
+)
+
+var writeParityManifest = flag.Bool(
+	"parity-manifest",
+	false,
+	"rewrite the parity manifest from parityCases, for tools/parity to render the goldens of",
+)
+
+// parityDir is the golden-file corpus, generated by tools/parity with the reference JS library
+var parityDir = filepath.Join("testdata", "parity")
+
+// referenceGenerator prefixes the generator of a corpus rendered by the reference JS library.
+// Any other corpus can't tell about parity, so it's not checked against
+const referenceGenerator = "boring-avatars@"
+
+// maxReportedDiffs caps the reported differences per case
+const maxReportedDiffs = 10
+
+// parityManifest describes the golden-file corpus
+type parityManifest struct {
+	// The library (and version) the goldens were generated with
+	Generator string `json:"generator,omitempty"`
+
+	Cases []parityCase `json:"cases"`
+}
+
+// parityCase is a single golden file, and the params it was generated with
+type parityCase struct {
+	File   string  `json:"file"`
+	Style  Style   `json:"style"`
+	Name   string  `json:"name"`
+	Colors Palette `json:"colors"`
+	Size   int     `json:"size"`
+	Square bool    `json:"square"`
+}
+
+// parityGenerators are the per-style generators under test
+var parityGenerators = map[Style]func(string, Palette, int, bool) string{
+	Beam:    GenerateBeam,
+	Bauhaus: GenerateBauhaus,
+	Marble:  GenerateMarble,
+	Pixel:   GeneratePixel,
+	Ring:    GenerateRing,
+	Sunset:  GenerateSunset,
+}
+
+// parityCases returns the corpus cases: names x palettes x sizes x square flags, for every style.
+// Sizes are always set, since the reference library defaults to 40px
+func parityCases() []parityCase {
+	var (
+		names = []string{
+			"Mary Baker",
+			"Amelia Earhart",
+			"Grace Hopper",
+			"Maria Mitchell",
+			"Margaret Brent",
+			"john.doe@example.com",
+			"12345",
+			"a",
+			"Zoë Ångström",
+			"A very long name that overflows the 32-bit hash many, many times over",
+		}
+		palettes = []Palette{
+			{"#92A1C6", "#146A7C", "#F0AB3D", "#C271B4", "#C20D90"}, // the reference library default
+			{"#0a0310", "#49007e", "#ff005b", "#ff7d10", "#ffb238"},
+		}
+		variants = []struct {
+			size   int
+			square bool
+		}{
+			{40, false},
+			{80, true},
+			{128, false},
+		}
+		cases []parityCase
+	)
+
+	for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+		i := 0
+
+		for _, name := range names {
+			for _, palette := range palettes {
+				for _, v := range variants {
+					cases = append(cases, parityCase{
+						File:   fmt.Sprintf("%s/%03d.svg", style, i),
+						Style:  style,
+						Name:   name,
+						Colors: palette,
+						Size:   v.size,
+						Square: v.square,
+					})
+
+					i++
+				}
+			}
+		}
+	}
+
+	return cases
+}
+
+// writeManifest writes out the corpus manifest, without any generator or goldens:
+// those are rendered by tools/parity, with the reference JS library
+func writeManifest(t *testing.T) {
+	t.Helper()
+
+	encoded, err := json.MarshalIndent(parityManifest{Cases: parityCases()}, "", "  ")
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(parityDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(parityDir, "manifest.json"), append(encoded, '\n'), 0o600))
+}
+
+func TestParity(t *testing.T) {
+	t.Parallel()
+
+	if *writeParityManifest {
+		writeManifest(t)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(parityDir, "manifest.json"))
+	require.NoError(t, err)
+
+	var m parityManifest
+
+	require.NoError(t, json.Unmarshal(raw, &m))
+	require.NotEmpty(t, m.Cases)
+
+	if !strings.HasPrefix(m.Generator, referenceGenerator) {
+		t.Fatal("the parity goldens haven't been rendered by the reference JS library, run make parity")
+	}
+
+	for _, c := range m.Cases {
+		t.Run(c.File, func(t *testing.T) {
+			t.Parallel()
+
+			generate, ok := parityGenerators[c.Style]
+			require.True(t, ok, "unknown style %q", c.Style)
+
+			golden, err := os.ReadFile(filepath.Join(parityDir, filepath.FromSlash(c.File)))
+			require.NoError(t, err)
+
+			diffs, err := svgdiff.Compare(string(golden), generate(c.Name, c.Colors, c.Size, c.Square))
+			require.NoError(t, err)
+
+			if len(diffs) == 0 {
+				return
+			}
+
+			report := make([]string, 0, maxReportedDiffs)
+
+			for _, d := range diffs[:min(len(diffs), maxReportedDiffs)] {
+				report = append(report, "  "+d.String())
+			}
+
+			t.Errorf(
+				"%d difference(s) against %s (name %q, size %d, square %t):\n%s",
+				len(diffs), m.Generator, c.Name, c.Size, c.Square,
+				strings.Join(report, "\n"),
+			)
+		})
+	}
+}
@@ -1,8 +1,9 @@
 package avatars
 
 import (
-	"fmt"
-	"strings"
+	"io"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
 )
 
 const (
@@ -26,102 +27,53 @@ func buildPixelColors(id int, palette Palette) []string {
 	return out
 }
 
-// GeneratePixel returns an 8x8 pixel-art avatar SVG
-func GeneratePixel(name string, palette Palette, size int, square bool) string {
-	var (
-		id     = NameToID(name)
-		colors = buildPixelColors(id, palette)
-		maskID = fmt.Sprintf("mask_pixel_%d", id)
-	)
-
-	// Start building out the SVG
-	var b strings.Builder
-
-	if size > 0 {
-		// Custom size
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`,
-			pixelSize, pixelSize,
-			size, size,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg">`,
-			pixelSize, pixelSize,
-		)
-	}
-
-	// Mask group
-	_, _ = fmt.Fprintf(
-		&b,
-		`<mask id="%s" mask-type="alpha" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">`,
-		maskID,
-		pixelSize, pixelSize,
-	)
-
-	if square {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" fill="#FFFFFF"/>`,
-			pixelSize, pixelSize,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" rx="%d" fill="#FFFFFF"/>`,
-			pixelSize, pixelSize,
-			pixelSize*2,
-		)
-	}
-
-	b.WriteString(`</mask>`)
-
-	// Masked grid
-	_, _ = fmt.Fprintf(&b, `<g mask="url(#%s)">`, maskID)
+// pixelBackdrop returns the color of the pixel right below and right of the center
+// (x = 40, y = 40), the 4th pixel of the 3rd column after the first row
+func pixelBackdrop(id int, palette Palette) string {
+	return buildPixelColors(id, palette)[8+2*7+3]
+}
 
+// drawPixel draws the 8x8 pixel-art avatar content
+func drawPixel(id int, palette Palette) []scene.Node {
 	var (
-		cols = []int{0, 20, 40, 60, 10, 30, 50, 70} // even columns first, then odd
-		idx  = 0
+		colors = buildPixelColors(id, palette)
+		cols   = []float64{0, 20, 40, 60, 10, 30, 50, 70} // even columns first, then odd
+		nodes  = make([]scene.Node, 0, pixelElements)
 	)
 
-	writePixel := func(x, y int, fill string) {
-		switch {
-		case x == 0 && y == 0:
-			_, _ = fmt.Fprintf(&b, `<rect width="10" height="10" fill="%s"/>`, fill)
-		case x == 0:
-			_, _ = fmt.Fprintf(&b, `<rect y="%d" width="10" height="10" fill="%s"/>`, y, fill)
-		case y == 0:
-			_, _ = fmt.Fprintf(&b, `<rect x="%d" width="10" height="10" fill="%s"/>`, x, fill)
-		default:
-			_, _ = fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, x, y, fill)
-		}
+	pixel := func(x, y float64) {
+		nodes = append(nodes, &scene.Rect{
+			X:      x,
+			Y:      y,
+			Width:  10,
+			Height: 10,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(colors[len(nodes)]),
+			},
+		})
 	}
 
 	// Row 0 (y = 0)
 	for _, x := range cols {
-		writePixel(x, 0, colors[idx])
-
-		idx++
+		pixel(x, 0)
 	}
 
 	// The remaining rows, column by column (y = 10..70)
 	for _, x := range cols {
 		for y := 10; y < pixelSize; y += 10 {
-			writePixel(x, y, colors[idx])
-
-			idx++
+			pixel(x, float64(y))
 		}
 	}
 
-	// Group closure
-	b.WriteString(`</g>`)
+	return nodes
+}
 
-	// Final svg closure
-	b.WriteString(`</svg>`)
+// WritePixel streams an 8x8 pixel-art avatar SVG to the given writer
+func WritePixel(w io.Writer, name string, palette Palette, size int, square bool) error {
+	return write(w, Pixel, name, palette, size, square)
+}
 
-	return b.String()
+// GeneratePixel returns an 8x8 pixel-art avatar SVG
+func GeneratePixel(name string, palette Palette, size int, square bool) string {
+	return generate(Pixel, name, palette, size, square)
 }
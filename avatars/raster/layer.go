@@ -0,0 +1,329 @@
+package raster
+
+import (
+	"image"
+	"math"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// blendMode is a CSS mix-blend-mode
+type blendMode uint8
+
+const (
+	blendNormal blendMode = iota
+	blendMultiply
+	blendScreen
+	blendOverlay
+)
+
+// blendModeOf returns the scene blend mode.
+// Unsupported modes fall back to normal blending
+func blendModeOf(m scene.BlendMode) blendMode {
+	switch m {
+	case scene.BlendMultiply:
+		return blendMultiply
+	case scene.BlendScreen:
+		return blendScreen
+	case scene.BlendOverlay:
+		return blendOverlay
+	default:
+		return blendNormal
+	}
+}
+
+// blend returns the blended color component B(cb, cs)
+// for the non-premultiplied backdrop and source components
+func (m blendMode) blend(cb, cs float32) float32 {
+	switch m {
+	case blendMultiply:
+		return cb * cs
+	case blendScreen:
+		return cb + cs - cb*cs
+	case blendOverlay:
+		// Hard light, with the backdrop and source swapped
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+
+		return 1 - 2*(1-cb)*(1-cs)
+	default:
+		return cs
+	}
+}
+
+// layer is a premultiplied RGBA pixel buffer with floating point channels,
+// covering the given device space bounds
+type layer struct {
+	bounds image.Rectangle
+	pix    []float32
+}
+
+// newLayer creates a new, transparent layer
+func newLayer(bounds image.Rectangle) *layer {
+	return &layer{
+		bounds: bounds,
+		pix:    make([]float32, 4*bounds.Dx()*bounds.Dy()),
+	}
+}
+
+// offset returns the pixel offset of (x, y) in the buffer
+func (l *layer) offset(x, y int) int {
+	return 4 * ((y-l.bounds.Min.Y)*l.bounds.Dx() + (x - l.bounds.Min.X))
+}
+
+// compositePixel composites the premultiplied source pixel onto the destination pixel
+func compositePixel(d []float32, sr, sg, sb, sa float32, mode blendMode) {
+	if sa <= 0 {
+		return
+	}
+
+	da := d[3]
+
+	if mode == blendNormal || da <= 0 {
+		d[0] = sr + d[0]*(1-sa)
+		d[1] = sg + d[1]*(1-sa)
+		d[2] = sb + d[2]*(1-sa)
+		d[3] = sa + da*(1-sa)
+
+		return
+	}
+
+	// Separable blend mode, from the W3C compositing spec
+	for i, s := range [3]float32{sr, sg, sb} {
+		cb, cs := d[i]/da, s/sa
+
+		d[i] = s*(1-da) + d[i]*(1-sa) + sa*da*mode.blend(cb, cs)
+	}
+
+	d[3] = sa + da*(1-sa)
+}
+
+// paint paints the coverage mask with the given paint and blend mode
+func (l *layer) paint(cov *image.Alpha, p paint, mode blendMode) {
+	r := cov.Rect.Intersect(l.bounds)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			a := cov.Pix[cov.PixOffset(x, y)]
+			if a == 0 {
+				continue
+			}
+
+			var (
+				c = p.at(float64(x)+0.5, float64(y)+0.5)
+				k = c.a * float32(a) / 0xff
+				o = l.offset(x, y)
+			)
+
+			compositePixel(l.pix[o:o+4], c.r*k, c.g*k, c.b*k, k, mode)
+		}
+	}
+}
+
+// composite composites the source layer onto the layer
+func (l *layer) composite(src *layer, mode blendMode, opacity float32) {
+	r := src.bounds.Intersect(l.bounds)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var (
+				s = src.pix[src.offset(x, y):]
+				o = l.offset(x, y)
+			)
+
+			compositePixel(
+				l.pix[o:o+4],
+				s[0]*opacity, s[1]*opacity, s[2]*opacity, s[3]*opacity,
+				mode,
+			)
+		}
+	}
+}
+
+// mask multiplies the layer by the coverage values
+func (l *layer) mask(cov []float32) {
+	for i, v := range cov {
+		p := l.pix[4*i : 4*i+4]
+
+		p[0] *= v
+		p[1] *= v
+		p[2] *= v
+		p[3] *= v
+	}
+}
+
+// clip multiplies the layer by the coverage mask
+func (l *layer) clip(cov *image.Alpha) {
+	for y := l.bounds.Min.Y; y < l.bounds.Max.Y; y++ {
+		for x := l.bounds.Min.X; x < l.bounds.Max.X; x++ {
+			var a float32
+
+			if (image.Point{X: x, Y: y}).In(cov.Rect) {
+				a = float32(cov.Pix[cov.PixOffset(x, y)]) / 0xff
+			}
+
+			o := l.offset(x, y)
+
+			l.pix[o] *= a
+			l.pix[o+1] *= a
+			l.pix[o+2] *= a
+			l.pix[o+3] *= a
+		}
+	}
+}
+
+// blur applies a Gaussian blur with the given standard deviation (in px).
+// Per the SVG spec, deviations of 2 and above are approximated with three box blurs
+func (l *layer) blur(sigma float64) {
+	if sigma <= 0 {
+		return
+	}
+
+	w, h := l.bounds.Dx(), l.bounds.Dy()
+
+	// Horizontal passes, then vertical ones over the transposed pixels
+	blurRows(l.pix, w, h, sigma)
+
+	t := transpose(l.pix, w, h)
+	blurRows(t, h, w, sigma)
+
+	l.pix = transpose(t, h, w)
+}
+
+// blurRows blurs each row of the w x h pixel buffer
+func blurRows(pix []float32, w, h int, sigma float64) {
+	tmp := make([]float32, 4*w)
+
+	for y := 0; y < h; y++ {
+		line := pix[4*y*w : 4*(y+1)*w]
+
+		if sigma < 2 {
+			gaussian(line, tmp, sigma)
+
+			continue
+		}
+
+		for _, b := range boxes(sigma) {
+			box(line, tmp, b[0], b[1])
+		}
+	}
+}
+
+// transpose returns the transposed w x h pixel buffer
+func transpose(pix []float32, w, h int) []float32 {
+	out := make([]float32, len(pix))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			copy(out[4*(x*h+y):4*(x*h+y)+4], pix[4*(y*w+x):4*(y*w+x)+4])
+		}
+	}
+
+	return out
+}
+
+// boxes returns the three (left, right) box blur extents approximating
+// a Gaussian blur with the given deviation
+func boxes(sigma float64) [3][2]int {
+	var (
+		d = int(math.Floor(sigma*3*math.Sqrt(2*math.Pi)/4 + 0.5))
+		r = d / 2
+	)
+
+	if d%2 == 1 {
+		return [3][2]int{{r, r}, {r, r}, {r, r}}
+	}
+
+	// Even sizes are offset by half a pixel on alternating sides,
+	// followed by a centered box one pixel wider
+	return [3][2]int{{r, r - 1}, {r - 1, r}, {r, r}}
+}
+
+// box runs a box blur over a line of pixels, averaging
+// each pixel over [-left, +right]. The line is modified in place
+func box(line, tmp []float32, left, right int) {
+	var (
+		n              = len(line) / 4
+		inv            = 1 / float32(left+right+1)
+		s0, s1, s2, s3 float32
+	)
+
+	// Prime the window for the first pixel
+	for i := 0; i <= right && i < n; i++ {
+		p := line[4*i : 4*i+4]
+
+		s0, s1, s2, s3 = s0+p[0], s1+p[1], s2+p[2], s3+p[3]
+	}
+
+	for i := 0; i < n; i++ {
+		t := tmp[4*i : 4*i+4]
+
+		t[0], t[1], t[2], t[3] = s0*inv, s1*inv, s2*inv, s3*inv
+
+		// Slide the window
+		if j := i + right + 1; j < n {
+			p := line[4*j : 4*j+4]
+
+			s0, s1, s2, s3 = s0+p[0], s1+p[1], s2+p[2], s3+p[3]
+		}
+
+		if j := i - left; j >= 0 {
+			p := line[4*j : 4*j+4]
+
+			s0, s1, s2, s3 = s0-p[0], s1-p[1], s2-p[2], s3-p[3]
+		}
+	}
+
+	copy(line, tmp)
+}
+
+// gaussian convolves a line of pixels with a Gaussian kernel.
+// The line is modified in place
+func gaussian(line, tmp []float32, sigma float64) {
+	var (
+		n      = len(line) / 4
+		radius = int(math.Ceil(3 * sigma))
+		kernel = make([]float32, 2*radius+1)
+		total  float32
+	)
+
+	for i := range kernel {
+		x := float64(i - radius)
+
+		kernel[i] = float32(math.Exp(-x * x / (2 * sigma * sigma)))
+		total += kernel[i]
+	}
+
+	for i := 0; i < n; i++ {
+		var sum [4]float32
+
+		for k, weight := range kernel {
+			j := i + k - radius
+			if j < 0 || j >= n {
+				continue
+			}
+
+			for c := 0; c < 4; c++ {
+				sum[c] += line[4*j+c] * weight
+			}
+		}
+
+		for c := 0; c < 4; c++ {
+			tmp[4*i+c] = sum[c] / total
+		}
+	}
+
+	copy(line, tmp)
+}
+
+// image converts the layer into an 8-bit premultiplied RGBA image
+func (l *layer) image() *image.RGBA {
+	img := image.NewRGBA(l.bounds)
+
+	for i, v := range l.pix {
+		img.Pix[i] = uint8(math.Round(float64(min(max(v, 0), 1)) * 0xff))
+	}
+
+	return img
+}
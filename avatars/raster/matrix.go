@@ -0,0 +1,100 @@
+package raster
+
+import (
+	"math"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// point is a 2D point
+type point struct {
+	x, y float64
+}
+
+// matrix is a 2D affine transformation, in the SVG (a b c d e f) order
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// translate returns a translation matrix
+func translate(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// scale returns a scaling matrix
+func scale(x, y float64) matrix {
+	return matrix{x, 0, 0, y, 0, 0}
+}
+
+// rotate returns a rotation matrix (in degrees) around (cx, cy)
+func rotate(deg, cx, cy float64) matrix {
+	var (
+		rad      = deg * math.Pi / 180
+		sin, cos = math.Sincos(rad)
+	)
+
+	return translate(cx, cy).mul(matrix{cos, sin, -sin, cos, 0, 0}).mul(translate(-cx, -cy))
+}
+
+// mul returns the matrix product m * n, applying n first
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+// apply transforms the point
+func (m matrix) apply(p point) point {
+	return point{
+		x: m[0]*p.x + m[2]*p.y + m[4],
+		y: m[1]*p.x + m[3]*p.y + m[5],
+	}
+}
+
+// invert returns the inverse matrix.
+// Singular matrices invert to the identity
+func (m matrix) invert() matrix {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return identity
+	}
+
+	return matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}
+}
+
+// factor returns the average linear scale factor of the matrix
+func (m matrix) factor() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// transformMatrix returns the matrix for the scene transform
+func transformMatrix(t scene.Transform) matrix {
+	m := identity
+
+	for _, op := range t {
+		switch op := op.(type) {
+		case scene.Translate:
+			m = m.mul(translate(op.X, op.Y))
+		case scene.Rotate:
+			m = m.mul(rotate(op.Angle, op.CX, op.CY))
+		case scene.Scale:
+			m = m.mul(scale(op.Factor, op.Factor))
+		case scene.ScaleXY:
+			m = m.mul(scale(op.X, op.Y))
+		}
+	}
+
+	return m
+}
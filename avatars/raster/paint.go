@@ -0,0 +1,115 @@
+package raster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rgba is a non-premultiplied RGBA color, with channels in [0, 1]
+type rgba struct {
+	r, g, b, a float32
+}
+
+var namedColors = map[string]rgba{
+	"black":       {0, 0, 0, 1},
+	"white":       {1, 1, 1, 1},
+	"transparent": {0, 0, 0, 0},
+}
+
+// parseColor parses a #RGB, #RRGGBB or basic named color
+func parseColor(s string) (rgba, error) {
+	s = strings.TrimSpace(s)
+
+	if c, ok := namedColors[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !strings.HasPrefix(s, "#") || len(hex) != 6 || err != nil {
+		return rgba{}, fmt.Errorf("unsupported color %q", s)
+	}
+
+	return rgba{
+		r: float32(v>>16&0xff) / 0xff,
+		g: float32(v>>8&0xff) / 0xff,
+		b: float32(v&0xff) / 0xff,
+		a: 1,
+	}, nil
+}
+
+// lerp linearly interpolates between the two colors
+func lerp(a, b rgba, t float32) rgba {
+	return rgba{
+		r: a.r + (b.r-a.r)*t,
+		g: a.g + (b.g-a.g)*t,
+		b: a.b + (b.b-a.b)*t,
+		a: a.a + (b.a-a.a)*t,
+	}
+}
+
+// stop is a single gradient color stop
+type stop struct {
+	offset float64
+	color  rgba
+}
+
+// gradient is a linear gradient, evaluated in device space
+type gradient struct {
+	inv            matrix // device to gradient space
+	x1, y1, x2, y2 float64
+	stops          []stop
+}
+
+// at returns the gradient color at the device space point.
+// The gradient is padded beyond its end stops
+func (g *gradient) at(x, y float64) rgba {
+	var (
+		p      = g.inv.apply(point{x, y})
+		dx, dy = g.x2 - g.x1, g.y2 - g.y1
+		t      float64
+	)
+
+	if l := dx*dx + dy*dy; l > 0 {
+		t = ((p.x-g.x1)*dx + (p.y-g.y1)*dy) / l
+	}
+
+	if t <= g.stops[0].offset {
+		return g.stops[0].color
+	}
+
+	for i := 1; i < len(g.stops); i++ {
+		a, b := g.stops[i-1], g.stops[i]
+		if t > b.offset {
+			continue
+		}
+
+		if b.offset == a.offset {
+			return b.color
+		}
+
+		return lerp(a.color, b.color, float32((t-a.offset)/(b.offset-a.offset)))
+	}
+
+	return g.stops[len(g.stops)-1].color
+}
+
+// paint is either a solid color, or a gradient
+type paint struct {
+	gradient *gradient
+	color    rgba
+}
+
+// at returns the paint color at the device space point
+func (p paint) at(x, y float64) rgba {
+	if p.gradient != nil {
+		return p.gradient.at(x, y)
+	}
+
+	return p.color
+}
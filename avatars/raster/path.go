@@ -0,0 +1,571 @@
+package raster
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// segmentKind is the path segment type
+type segmentKind uint8
+
+const (
+	moveTo segmentKind = iota
+	lineTo
+	cubeTo
+	closePath
+)
+
+// segment is a single absolute path segment.
+// Line and move segments only use the first point,
+// while cubic segments use all three (two controls, then the end)
+type segment struct {
+	kind segmentKind
+	pts  [3]point
+}
+
+// path is a sequence of absolute path segments
+type path []segment
+
+func (p *path) moveTo(a point) {
+	*p = append(*p, segment{kind: moveTo, pts: [3]point{a}})
+}
+
+func (p *path) lineTo(a point) {
+	*p = append(*p, segment{kind: lineTo, pts: [3]point{a}})
+}
+
+func (p *path) cubeTo(c1, c2, a point) {
+	*p = append(*p, segment{kind: cubeTo, pts: [3]point{c1, c2, a}})
+}
+
+func (p *path) close() {
+	*p = append(*p, segment{kind: closePath})
+}
+
+// kappa is the cubic Bézier control distance approximating a quarter circle
+const kappa = 0.5522847498307936
+
+// ellipsePath returns a closed ellipse path
+func ellipsePath(cx, cy, rx, ry float64) path {
+	var (
+		p  path
+		kx = rx * kappa
+		ky = ry * kappa
+	)
+
+	p.moveTo(point{cx + rx, cy})
+	p.cubeTo(point{cx + rx, cy + ky}, point{cx + kx, cy + ry}, point{cx, cy + ry})
+	p.cubeTo(point{cx - kx, cy + ry}, point{cx - rx, cy + ky}, point{cx - rx, cy})
+	p.cubeTo(point{cx - rx, cy - ky}, point{cx - kx, cy - ry}, point{cx, cy - ry})
+	p.cubeTo(point{cx + kx, cy - ry}, point{cx + rx, cy - ky}, point{cx + rx, cy})
+	p.close()
+
+	return p
+}
+
+// rectPath returns a closed, optionally rounded, rectangle path.
+// The radii are clamped to half the rectangle dimensions
+func rectPath(x, y, w, h, rx, ry float64) path {
+	var p path
+
+	rx = math.Min(math.Max(rx, 0), w/2)
+	ry = math.Min(math.Max(ry, 0), h/2)
+
+	if rx == 0 || ry == 0 {
+		p.moveTo(point{x, y})
+		p.lineTo(point{x + w, y})
+		p.lineTo(point{x + w, y + h})
+		p.lineTo(point{x, y + h})
+		p.close()
+
+		return p
+	}
+
+	kx, ky := rx*kappa, ry*kappa
+
+	p.moveTo(point{x + rx, y})
+	p.lineTo(point{x + w - rx, y})
+	p.cubeTo(point{x + w - rx + kx, y}, point{x + w, y + ry - ky}, point{x + w, y + ry})
+	p.lineTo(point{x + w, y + h - ry})
+	p.cubeTo(point{x + w, y + h - ry + ky}, point{x + w - rx + kx, y + h}, point{x + w - rx, y + h})
+	p.lineTo(point{x + rx, y + h})
+	p.cubeTo(point{x + rx - kx, y + h}, point{x, y + h - ry + ky}, point{x, y + h - ry})
+	p.lineTo(point{x, y + ry})
+	p.cubeTo(point{x, y + ry - ky}, point{x + rx - kx, y}, point{x + rx, y})
+	p.close()
+
+	return p
+}
+
+// pathParser tokenizes SVG path data (and other number lists)
+type pathParser struct {
+	s string
+	i int
+}
+
+// skip skips over whitespace and commas
+func (p *pathParser) skip() {
+	for p.i < len(p.s) {
+		switch p.s[p.i] {
+		case ' ', '\t', '\r', '\n', ',':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+// more checks if a number follows
+func (p *pathParser) more() bool {
+	p.skip()
+
+	if p.i >= len(p.s) {
+		return false
+	}
+
+	c := p.s[p.i]
+
+	return c == '-' || c == '+' || c == '.' || isDigit(c)
+}
+
+// number reads the next number
+func (p *pathParser) number() (float64, error) {
+	p.skip()
+
+	start := p.i
+
+	if p.i < len(p.s) && (p.s[p.i] == '-' || p.s[p.i] == '+') {
+		p.i++
+	}
+
+	for p.i < len(p.s) && isDigit(p.s[p.i]) {
+		p.i++
+	}
+
+	if p.i < len(p.s) && p.s[p.i] == '.' {
+		p.i++
+
+		for p.i < len(p.s) && isDigit(p.s[p.i]) {
+			p.i++
+		}
+	}
+
+	// Exponent, only if actually followed by digits
+	if p.i < len(p.s) && (p.s[p.i] == 'e' || p.s[p.i] == 'E') {
+		j := p.i + 1
+		if j < len(p.s) && (p.s[j] == '-' || p.s[j] == '+') {
+			j++
+		}
+
+		if j < len(p.s) && isDigit(p.s[j]) {
+			p.i = j
+
+			for p.i < len(p.s) && isDigit(p.s[p.i]) {
+				p.i++
+			}
+		}
+	}
+
+	v, err := strconv.ParseFloat(p.s[start:p.i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number at offset %d in %q", start, p.s)
+	}
+
+	return v, nil
+}
+
+// flag reads the next arc flag, which may not be separated from what follows
+func (p *pathParser) flag() (bool, error) {
+	p.skip()
+
+	if p.i >= len(p.s) || (p.s[p.i] != '0' && p.s[p.i] != '1') {
+		return false, fmt.Errorf("invalid arc flag at offset %d in %q", p.i, p.s)
+	}
+
+	p.i++
+
+	return p.s[p.i-1] == '1', nil
+}
+
+// numbers reads n numbers
+func (p *pathParser) numbers(n int) ([]float64, error) {
+	out := make([]float64, n)
+
+	for i := range out {
+		v, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = v
+	}
+
+	return out, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// parsePath parses SVG path data into absolute segments.
+// Quadratic curves and elliptical arcs are converted to cubic curves
+func parsePath(d string) (path, error) {
+	var (
+		out path
+		p   = &pathParser{s: d}
+
+		cur, start point
+		ctrl       point // last control point, for smooth curves
+		prev       byte  // previous command
+		cmd        byte
+	)
+
+	for {
+		p.skip()
+
+		if p.i >= len(p.s) {
+			return out, nil
+		}
+
+		switch c := p.s[p.i]; {
+		case isLetter(c):
+			cmd = c
+			p.i++
+		case cmd == 0:
+			return nil, fmt.Errorf("path data %q must start with a command", d)
+		case cmd == 'z' || cmd == 'Z':
+			return nil, fmt.Errorf("unexpected number after closepath in %q", d)
+		case cmd == 'M':
+			// Implicit repetitions of a moveto are linetos
+			cmd = 'L'
+		case cmd == 'm':
+			cmd = 'l'
+		}
+
+		rel := cmd >= 'a'
+		abs := func(x, y float64) point {
+			if rel {
+				return point{cur.x + x, cur.y + y}
+			}
+
+			return point{x, y}
+		}
+
+		switch cmd | 0x20 { // lower-case
+		case 'z':
+			out.close()
+
+			cur = start
+		case 'm', 'l', 't':
+			v, err := p.numbers(2)
+			if err != nil {
+				return nil, err
+			}
+
+			a := abs(v[0], v[1])
+
+			switch cmd | 0x20 {
+			case 'm':
+				out.moveTo(a)
+
+				start = a
+			case 'l':
+				out.lineTo(a)
+			default:
+				q := cur
+				if prev|0x20 == 'q' || prev|0x20 == 't' {
+					q = point{2*cur.x - ctrl.x, 2*cur.y - ctrl.y}
+				}
+
+				out.cubeTo(quadToCubic(cur, q, a))
+
+				ctrl = q
+			}
+
+			cur = a
+		case 'h', 'v':
+			v, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+
+			a := cur
+
+			switch {
+			case cmd == 'h':
+				a.x += v
+			case cmd == 'H':
+				a.x = v
+			case cmd == 'v':
+				a.y += v
+			default:
+				a.y = v
+			}
+
+			out.lineTo(a)
+
+			cur = a
+		case 'c':
+			v, err := p.numbers(6)
+			if err != nil {
+				return nil, err
+			}
+
+			c1, c2, a := abs(v[0], v[1]), abs(v[2], v[3]), abs(v[4], v[5])
+			out.cubeTo(c1, c2, a)
+
+			cur, ctrl = a, c2
+		case 's':
+			v, err := p.numbers(4)
+			if err != nil {
+				return nil, err
+			}
+
+			c1 := cur
+			if prev|0x20 == 'c' || prev|0x20 == 's' {
+				c1 = point{2*cur.x - ctrl.x, 2*cur.y - ctrl.y}
+			}
+
+			c2, a := abs(v[0], v[1]), abs(v[2], v[3])
+			out.cubeTo(c1, c2, a)
+
+			cur, ctrl = a, c2
+		case 'q':
+			v, err := p.numbers(4)
+			if err != nil {
+				return nil, err
+			}
+
+			q, a := abs(v[0], v[1]), abs(v[2], v[3])
+			out.cubeTo(quadToCubic(cur, q, a))
+
+			cur, ctrl = a, q
+		case 'a':
+			radii, err := p.numbers(3)
+			if err != nil {
+				return nil, err
+			}
+
+			large, err := p.flag()
+			if err != nil {
+				return nil, err
+			}
+
+			sweep, err := p.flag()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := p.numbers(2)
+			if err != nil {
+				return nil, err
+			}
+
+			a := abs(v[0], v[1])
+
+			for _, c := range arcToCubics(cur, radii[0], radii[1], radii[2], large, sweep, a) {
+				out.cubeTo(c[0], c[1], c[2])
+			}
+
+			cur = a
+		default:
+			return nil, fmt.Errorf("unsupported path command %q", cmd)
+		}
+
+		prev = cmd
+	}
+}
+
+// quadToCubic elevates a quadratic Bézier curve to a cubic one
+func quadToCubic(p0, q, p1 point) (point, point, point) {
+	return point{p0.x + 2.0/3*(q.x-p0.x), p0.y + 2.0/3*(q.y-p0.y)},
+		point{p1.x + 2.0/3*(q.x-p1.x), p1.y + 2.0/3*(q.y-p1.y)},
+		p1
+}
+
+// arcToCubics approximates an SVG elliptical arc with cubic Bézier curves,
+// following the endpoint to center parameterization conversion (SVG 1.1, F.6.5)
+func arcToCubics(p0 point, rx, ry, phiDeg float64, large, sweep bool, p1 point) [][3]point {
+	if p0 == p1 {
+		return nil
+	}
+
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return [][3]point{{p0, p1, p1}}
+	}
+
+	var (
+		sinPhi, cosPhi = math.Sincos(phiDeg * math.Pi / 180)
+
+		dx = (p0.x - p1.x) / 2
+		dy = (p0.y - p1.y) / 2
+
+		x1 = cosPhi*dx + sinPhi*dy
+		y1 = -sinPhi*dx + cosPhi*dy
+	)
+
+	// Scale up out-of-range radii
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	var (
+		num = rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+		den = rx*rx*y1*y1 + ry*ry*x1*x1
+		sq  = math.Max(num/den, 0)
+
+		coef = math.Sqrt(sq)
+	)
+
+	if large == sweep {
+		coef = -coef
+	}
+
+	var (
+		cxp = coef * rx * y1 / ry
+		cyp = -coef * ry * x1 / rx
+
+		cx = cosPhi*cxp - sinPhi*cyp + (p0.x+p1.x)/2
+		cy = sinPhi*cxp + cosPhi*cyp + (p0.y+p1.y)/2
+
+		ux, uy = (x1 - cxp) / rx, (y1 - cyp) / ry
+		vx, vy = (-x1 - cxp) / rx, (-y1 - cyp) / ry
+
+		theta = math.Atan2(uy, ux)
+		delta = math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	)
+
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	var (
+		n     = int(math.Ceil(math.Abs(delta)/(math.Pi/2) - 1e-9))
+		step  = delta / float64(n)
+		alpha = 4.0 / 3 * math.Tan(step/4)
+
+		at = func(t float64) (point, point) {
+			sin, cos := math.Sincos(t)
+
+			// Point on the ellipse, and its derivative
+			return point{
+				cx + rx*cos*cosPhi - ry*sin*sinPhi,
+				cy + rx*cos*sinPhi + ry*sin*cosPhi,
+			}, point{
+				-rx*sin*cosPhi - ry*cos*sinPhi,
+				-rx*sin*sinPhi + ry*cos*cosPhi,
+			}
+		}
+
+		out = make([][3]point, 0, n)
+	)
+
+	for i := 0; i < n; i++ {
+		var (
+			t1 = theta + float64(i)*step
+			t2 = t1 + step
+
+			e1, d1 = at(t1)
+			e2, d2 = at(t2)
+		)
+
+		if i == n-1 {
+			e2 = p1 // avoid accumulated drift at the end point
+		}
+
+		out = append(out, [3]point{
+			{e1.x + alpha*d1.x, e1.y + alpha*d1.y},
+			{e2.x - alpha*d2.x, e2.y - alpha*d2.y},
+			e2,
+		})
+	}
+
+	return out
+}
+
+// flatten converts the path into device space polylines, using the given transform.
+// The closed flag of each polyline is reported alongside it
+func flatten(p path, m matrix) ([][]point, []bool) {
+	var (
+		lines  [][]point
+		closed []bool
+
+		cur []point
+	)
+
+	flush := func(isClosed bool) {
+		if len(cur) > 0 {
+			lines = append(lines, cur)
+			closed = append(closed, isClosed)
+		}
+
+		cur = nil
+	}
+
+	for _, s := range p {
+		switch s.kind {
+		case moveTo:
+			if len(cur) > 1 {
+				flush(false)
+			}
+
+			cur = []point{m.apply(s.pts[0])}
+		case lineTo:
+			cur = append(cur, m.apply(s.pts[0]))
+		case cubeTo:
+			if len(cur) == 0 {
+				cur = []point{{}}
+			}
+
+			var (
+				p0 = cur[len(cur)-1]
+				c1 = m.apply(s.pts[0])
+				c2 = m.apply(s.pts[1])
+				p1 = m.apply(s.pts[2])
+
+				// Subdivide by the control polygon length (~1 step per device px)
+				length = dist(p0, c1) + dist(c1, c2) + dist(c2, p1)
+				n      = int(math.Min(math.Max(math.Ceil(length), 4), 256))
+			)
+
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				mt := 1 - t
+
+				cur = append(cur, point{
+					mt*mt*mt*p0.x + 3*mt*mt*t*c1.x + 3*mt*t*t*c2.x + t*t*t*p1.x,
+					mt*mt*mt*p0.y + 3*mt*mt*t*c1.y + 3*mt*t*t*c2.y + t*t*t*p1.y,
+				})
+			}
+		case closePath:
+			if len(cur) > 0 {
+				start := cur[0]
+
+				flush(true)
+
+				// A path may continue from the start of the closed subpath
+				cur = []point{start}
+			}
+		}
+	}
+
+	// Discard dangling single points left over from closing
+	if len(cur) > 1 {
+		flush(false)
+	}
+
+	return lines, closed
+}
+
+func dist(a, b point) float64 {
+	return math.Hypot(b.x-a.x, b.y-a.y)
+}
@@ -0,0 +1,88 @@
+// Package raster renders avatars into bitmap images (and PNGs),
+// in pure Go and without any external tools
+package raster
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/sig-0/boring-avatars-go/avatars"
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// DefaultSize is the image width and height, in px,
+// used when the options don't specify a size
+const DefaultSize = 80
+
+var ErrInvalidSize = errors.New("invalid image size, expected a positive value")
+
+// Render renders the avatar into an image of opts.Size x opts.Size px
+// (or DefaultSize, if the size is not set)
+func Render(opts avatars.Options) (*image.RGBA, error) {
+	s, err := avatars.Build(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	size := opts.Size
+	if size == 0 {
+		size = DefaultSize
+	}
+
+	return RenderScene(s, size)
+}
+
+// WritePNG renders the avatar and writes it out as a PNG
+func WritePNG(w io.Writer, opts avatars.Options) error {
+	img, err := Render(opts)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// RenderScene rasterizes the avatar scene into an image of size x size px.
+// It supports the full scene model: shapes and paths, transforms, luminance
+// and alpha masks, linear gradients, Gaussian blur filters and separable blend modes.
+// Text is always set in Go Bold, which covers the Latin, Greek and Cyrillic scripts,
+// and animated scenes are rendered still
+func RenderScene(s *scene.Scene, size int) (*image.RGBA, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSize, size)
+	}
+
+	r, err := newRenderer(s, size)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := r.render(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render scene, %w", err)
+	}
+
+	return l.image(), nil
+}
+
+// PNGEncoder encodes scenes as PNG images of the scene size
+// (or DefaultSize, if the scene is not sized)
+type PNGEncoder struct{}
+
+// Encode rasterizes the scene and writes it out as a PNG
+func (PNGEncoder) Encode(w io.Writer, s *scene.Scene) error {
+	size := s.Size
+	if size == 0 {
+		size = DefaultSize
+	}
+
+	img, err := RenderScene(s, size)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
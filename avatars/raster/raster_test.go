@@ -0,0 +1,416 @@
+package raster
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/avatars"
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the SVG inputs of the reference images in testdata")
+
+var testPalette = avatars.Palette{"#92A1C6", "#146A7C", "#F0AB3D", "#C271B4", "#C20D90"}
+
+// The tolerances against the reference images, which come from an independent renderer (resvg).
+// Anti-aliasing differs between renderers, so a share of the pixels (along the shape edges)
+// may exceed the channel tolerance
+const (
+	maxChannelDiff = 8
+	maxEdgeRatio   = 0.03
+)
+
+// TestRender_Reference compares the rasterized avatars with the reference images,
+// rendered from the SVG inputs in testdata by resvg (see tools/raster)
+func TestRender_Reference(t *testing.T) {
+	t.Parallel()
+
+	testTable := []avatars.Options{
+		{Style: avatars.Beam, Name: "Mary Baker"},
+		{Style: avatars.Beam, Name: "Mary Roebling", Square: true},
+		{Style: avatars.Bauhaus, Name: "Mary Baker"},
+		{Style: avatars.Marble, Name: "Mary Baker"},
+		{Style: avatars.Marble, Name: "Margaret Brent", Square: true},
+		{Style: avatars.Pixel, Name: "Mary Baker"},
+		{Style: avatars.Ring, Name: "Mary Baker"},
+		{Style: avatars.Sunset, Name: "Mary Baker"},
+	}
+
+	for _, opts := range testTable {
+		opts.Palette = testPalette
+		opts.Size = 64
+
+		name := fmt.Sprintf(
+			"%s_%s_%t",
+			opts.Style,
+			strings.ReplaceAll(strings.ToLower(opts.Name), " ", "_"),
+			opts.Square,
+		)
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			svg, err := avatars.Render(opts)
+			require.NoError(t, err)
+
+			var (
+				svgPath = filepath.Join("testdata", name+".svg")
+				refPath = filepath.Join("testdata", name+".png")
+			)
+
+			if *update {
+				require.NoError(t, os.WriteFile(svgPath, []byte(svg), 0o600))
+
+				return
+			}
+
+			// A changed input makes the reference image stale
+			input, err := os.ReadFile(svgPath)
+			require.NoError(t, err)
+			require.Equal(t, string(input), svg, "stale SVG input, run the test with -update and make raster")
+
+			f, err := os.Open(refPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("no reference image (%s), run make raster", refPath)
+			}
+
+			require.NoError(t, err)
+
+			defer f.Close()
+
+			ref, err := png.Decode(f)
+			require.NoError(t, err)
+
+			img, err := Render(opts)
+			require.NoError(t, err)
+
+			require.Equal(t, ref.Bounds(), img.Bounds())
+
+			var (
+				bounds = ref.Bounds()
+				diffs  = 0
+			)
+
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					var (
+						want = color.RGBAModel.Convert(ref.At(x, y)).(color.RGBA)
+						got  = img.RGBAAt(x, y)
+					)
+
+					if !closeTo(want, got) {
+						diffs++
+					}
+				}
+			}
+
+			assert.LessOrEqual(t, float64(diffs)/float64(bounds.Dx()*bounds.Dy()), maxEdgeRatio)
+		})
+	}
+}
+
+// closeTo checks if the two colors are within the channel tolerance
+func closeTo(a, b color.RGBA) bool {
+	diff := func(x, y uint8) int {
+		if x > y {
+			return int(x - y)
+		}
+
+		return int(y - x)
+	}
+
+	return diff(a.R, b.R) <= maxChannelDiff &&
+		diff(a.G, b.G) <= maxChannelDiff &&
+		diff(a.B, b.B) <= maxChannelDiff &&
+		diff(a.A, b.A) <= maxChannelDiff
+}
+
+// hexColor converts a #RRGGBB color to an opaque color.RGBA
+func hexColor(t *testing.T, hex string) color.RGBA {
+	t.Helper()
+
+	var c color.RGBA
+
+	_, err := fmt.Sscanf(hex, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	require.NoError(t, err)
+
+	c.A = 0xff
+
+	return c
+}
+
+func TestRender_Geometry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round mask", func(t *testing.T) {
+		t.Parallel()
+
+		img, err := Render(avatars.Options{Style: avatars.Ring, Name: "Mary Baker", Size: 90})
+		require.NoError(t, err)
+
+		assert.Equal(t, color.RGBA{}, img.RGBAAt(0, 0))
+		assert.Equal(t, color.RGBA{}, img.RGBAAt(89, 89))
+		assert.Equal(t, uint8(0xff), img.RGBAAt(45, 2).A)
+	})
+
+	t.Run("square mask", func(t *testing.T) {
+		t.Parallel()
+
+		img, err := Render(avatars.Options{Style: avatars.Ring, Name: "Mary Baker", Size: 90, Square: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, uint8(0xff), img.RGBAAt(0, 0).A)
+		assert.Equal(t, uint8(0xff), img.RGBAAt(89, 89).A)
+	})
+
+	t.Run("hexagon mask", func(t *testing.T) {
+		t.Parallel()
+
+		img, err := Render(avatars.Options{Style: avatars.Ring, Name: "Mary Baker", Size: 90, Shape: avatars.ShapeHexagon})
+		require.NoError(t, err)
+
+		// The pointy top and bottom are clipped at the sides, but not the flat left and right edges
+		assert.Equal(t, color.RGBA{}, img.RGBAAt(5, 2))
+		assert.Equal(t, uint8(0xff), img.RGBAAt(45, 2).A)
+		assert.Equal(t, uint8(0xff), img.RGBAAt(9, 45).A)
+		assert.Equal(t, color.RGBA{}, img.RGBAAt(2, 45))
+	})
+
+	t.Run("ring center", func(t *testing.T) {
+		t.Parallel()
+
+		// The center circle uses the fifth shuffled color
+		var (
+			id   = avatars.NameToID("Mary Baker")
+			want = hexColor(t, testPalette[(id+4)%len(testPalette)])
+		)
+
+		img, err := Render(avatars.Options{
+			Style:   avatars.Ring,
+			Name:    "Mary Baker",
+			Palette: testPalette,
+			Size:    180,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, want, img.RGBAAt(90, 90))
+	})
+
+	t.Run("pixel grid", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			id  = avatars.NameToID("Mary Baker")
+			img = must(t, avatars.Options{
+				Style:   avatars.Pixel,
+				Name:    "Mary Baker",
+				Palette: testPalette,
+				Size:    80,
+				Square:  true,
+			})
+		)
+
+		// The first row is drawn first, followed by the remaining rows column by column
+		cols := []int{0, 20, 40, 60, 10, 30, 50, 70}
+
+		for i, x := range cols {
+			want := hexColor(t, testPalette[(id%(i+1))%len(testPalette)])
+
+			assert.Equal(t, want, img.RGBAAt(x+5, 5), "pixel %d", i)
+		}
+	})
+
+	t.Run("sizes", func(t *testing.T) {
+		t.Parallel()
+
+		for _, size := range []int{1, 17, 512} {
+			img := must(t, avatars.Options{Style: avatars.Marble, Name: "Mary Baker", Size: size})
+
+			assert.Equal(t, image.Rect(0, 0, size, size), img.Bounds())
+		}
+
+		img := must(t, avatars.Options{Style: avatars.Marble, Name: "Mary Baker"})
+
+		assert.Equal(t, image.Rect(0, 0, DefaultSize, DefaultSize), img.Bounds())
+	})
+}
+
+// must renders the avatar, failing the test on error
+func must(t *testing.T, opts avatars.Options) *image.RGBA {
+	t.Helper()
+
+	img, err := Render(opts)
+	require.NoError(t, err)
+
+	return img
+}
+
+func TestRenderScene(t *testing.T) {
+	t.Parallel()
+
+	build := func(t *testing.T) *scene.Scene {
+		t.Helper()
+
+		s, err := avatars.Build(avatars.Options{Style: avatars.Beam, Name: "Mary Baker"})
+		require.NoError(t, err)
+
+		return s
+	}
+
+	t.Run("invalid size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := RenderScene(build(t), 0)
+
+		assert.ErrorIs(t, err, ErrInvalidSize)
+	})
+
+	t.Run("empty scene", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := RenderScene(&scene.Scene{}, 80)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid color", func(t *testing.T) {
+		t.Parallel()
+
+		s := build(t)
+		s.Recolor(func(string) string { return "teal" })
+
+		_, err := RenderScene(s, 80)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("recolored scene", func(t *testing.T) {
+		t.Parallel()
+
+		s := build(t)
+		s.Recolor(func(string) string { return "#FF0000" })
+
+		img, err := RenderScene(s, 80)
+		require.NoError(t, err)
+
+		assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, img.RGBAAt(40, 40))
+	})
+
+	t.Run("initials", func(t *testing.T) {
+		t.Parallel()
+
+		img, err := Render(avatars.Options{
+			Style:    avatars.Bauhaus,
+			Name:     "Mary Baker",
+			Palette:  avatars.Palette{"#FFFFFF"},
+			Size:     80,
+			Square:   true,
+			Initials: true,
+		})
+		require.NoError(t, err)
+
+		// The black initials are centered on the white background
+		var left, right int
+
+		for y := 0; y < 80; y++ {
+			for x := 0; x < 80; x++ {
+				if img.RGBAAt(x, y).R >= 0x80 {
+					continue
+				}
+
+				assert.True(t, x > 16 && x < 64 && y > 16 && y < 64, "ink outside the center (%d, %d)", x, y)
+
+				if x < 40 {
+					left++
+				} else {
+					right++
+				}
+			}
+		}
+
+		assert.Positive(t, left)
+		assert.Positive(t, right)
+		assert.InDelta(t, 1, float64(left)/float64(right), 0.5)
+	})
+
+	t.Run("animated scene", func(t *testing.T) {
+		t.Parallel()
+
+		// Animations are dropped, leaving the static avatar
+		for _, style := range []avatars.Style{
+			avatars.Beam, avatars.Bauhaus, avatars.Marble, avatars.Pixel, avatars.Ring, avatars.Sunset,
+		} {
+			opts := avatars.Options{Style: style, Name: "Mary Baker", Size: 80}
+
+			still, err := Render(opts)
+			require.NoError(t, err)
+
+			opts.Animate = true
+
+			animated, err := Render(opts)
+			require.NoError(t, err)
+
+			assert.Equal(t, still.Pix, animated.Pix, style)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Render(avatars.Options{Style: "cubist"})
+
+		assert.ErrorIs(t, err, avatars.ErrUnknownStyle)
+	})
+}
+
+func TestParsePath(t *testing.T) {
+	t.Parallel()
+
+	t.Run("compact arc flags", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := parsePath("M83 45a38 38 0 00-76 0h76z")
+		require.NoError(t, err)
+
+		// Half circle, as two quarter curves
+		require.Len(t, p, 5)
+
+		assert.Equal(t, moveTo, p[0].kind)
+		assert.InDelta(t, 7, p[2].pts[2].x, 1e-9)
+		assert.InDelta(t, 45, p[2].pts[2].y, 1e-9)
+		assert.InDelta(t, 7, p[1].pts[2].y, 1e-9) // the top of the circle
+		assert.Equal(t, closePath, p[4].kind)
+	})
+
+	t.Run("implicit commands and signs", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := parsePath("M0 46.75l14.108 38.129L78 86l-3.081-59.276-22.378 4.005z")
+		require.NoError(t, err)
+		require.Len(t, p, 6)
+
+		assert.InDelta(t, 52.541, p[4].pts[0].x, 1e-9)
+		assert.InDelta(t, 30.729, p[4].pts[0].y, 1e-9)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, d := range []string{"10 10", "M10", "M0 0 A1 1 0 2 1 5 5", "M0 0z 5"} {
+			_, err := parsePath(d)
+
+			assert.Error(t, err, d)
+		}
+	})
+}
@@ -0,0 +1,501 @@
+package raster
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"golang.org/x/image/vector"
+)
+
+// renderer rasterizes an avatar scene
+type renderer struct {
+	base    matrix          // viewBox to device space
+	canvas  image.Rectangle // output bounds
+	viewBox float64
+}
+
+// newRenderer prepares the renderer for the scene, at size x size px
+func newRenderer(s *scene.Scene, size int) (*renderer, error) {
+	if s.ViewBox <= 0 {
+		return nil, fmt.Errorf("invalid viewBox %v", s.ViewBox)
+	}
+
+	return &renderer{
+		base:    scale(float64(size)/s.ViewBox, float64(size)/s.ViewBox),
+		canvas:  image.Rect(0, 0, size, size),
+		viewBox: s.ViewBox,
+	}, nil
+}
+
+// render renders the scene
+func (r *renderer) render(s *scene.Scene) (*layer, error) {
+	dst := newLayer(r.canvas)
+
+	if err := r.renderNodes(dst, s.Children, r.base); err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+// renderNodes renders the nodes, in painting order
+func (r *renderer) renderNodes(dst *layer, nodes []scene.Node, ctm matrix) error {
+	for _, n := range nodes {
+		var err error
+
+		switch n := n.(type) {
+		case *scene.Group:
+			err = r.renderGroup(dst, n, ctm)
+		case *scene.Rect:
+			err = r.renderShape(dst, rectPath(n.X, n.Y, n.Width, n.Height, n.RX, n.RX), &n.Attrs, ctm)
+		case *scene.Circle:
+			err = r.renderShape(dst, ellipsePath(n.CX, n.CY, n.R, n.R), &n.Attrs, ctm)
+		case *scene.Line:
+			var p path
+
+			p.moveTo(point{n.X1, n.Y1})
+			p.lineTo(point{n.X2, n.Y2})
+
+			// Lines are never filled
+			attrs := n.Attrs
+			attrs.Fill = scene.None
+
+			err = r.renderShape(dst, p, &attrs, ctm)
+		case *scene.Path:
+			var p path
+
+			if p, err = parsePath(n.D); err == nil {
+				err = r.renderShape(dst, p, &n.Attrs, ctm)
+			}
+		case *scene.Text:
+			var p path
+
+			if p, err = textPath(n); err == nil {
+				err = r.renderShape(dst, p, &n.Attrs, ctm)
+			}
+		default:
+			err = fmt.Errorf("unsupported node %T", n)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderGroup renders a group, isolating it when it's masked
+func (r *renderer) renderGroup(dst *layer, g *scene.Group, ctm matrix) error {
+	ctm = ctm.mul(transformMatrix(g.Transform))
+
+	if g.Mask == nil {
+		return r.renderNodes(dst, g.Children, ctm)
+	}
+
+	content := newLayer(dst.bounds)
+
+	if err := r.renderNodes(content, g.Children, ctm); err != nil {
+		return err
+	}
+
+	cov, err := r.maskCoverage(g.Mask, dst.bounds, ctm)
+	if err != nil {
+		return err
+	}
+
+	content.mask(cov)
+	dst.composite(content, blendNormal, 1)
+
+	return nil
+}
+
+// maskCoverage renders the mask into per-pixel coverage values.
+// The mask region is the entire canvas, in the user space of the masked group
+func (r *renderer) maskCoverage(m *scene.Mask, bounds image.Rectangle, ctm matrix) ([]float32, error) {
+	content := newLayer(bounds)
+
+	if err := r.renderNodes(content, m.Children, ctm); err != nil {
+		return nil, err
+	}
+
+	var (
+		cov    = make([]float32, bounds.Dx()*bounds.Dy())
+		region = fillCoverage(bounds, rectPath(0, 0, r.viewBox, r.viewBox, 0, 0), ctm)
+	)
+
+	for i := range cov {
+		p := content.pix[4*i : 4*i+4]
+
+		if m.Type == scene.MaskAlpha {
+			cov[i] = p[3]
+		} else {
+			// Luminance of the premultiplied color
+			cov[i] = 0.2125*p[0] + 0.7154*p[1] + 0.0721*p[2]
+		}
+
+		cov[i] *= float32(region.Pix[i]) / 0xff
+	}
+
+	return cov, nil
+}
+
+// renderShape renders a basic shape or path, given in user space
+func (r *renderer) renderShape(dst *layer, p path, a *scene.Attrs, ctm matrix) error {
+	ctm = ctm.mul(transformMatrix(a.Transform))
+
+	fill, hasFill, err := paintOf(a.Fill, ctm)
+	if err != nil {
+		return err
+	}
+
+	stroke, hasStroke, err := paintOf(a.Stroke, ctm)
+	if err != nil {
+		return err
+	}
+
+	strokeWidth := a.StrokeWidth
+	if strokeWidth == 0 {
+		strokeWidth = 1
+	}
+
+	if !hasFill && (!hasStroke || strokeWidth <= 0) {
+		return nil
+	}
+
+	var (
+		sh = &shape{
+			path:        p,
+			ctm:         ctm,
+			fill:        fill,
+			hasFill:     hasFill,
+			stroke:      stroke,
+			hasStroke:   hasStroke,
+			strokeWidth: strokeWidth,
+			roundCap:    a.LineCap == scene.CapRound,
+		}
+		blend = blendModeOf(a.BlendMode)
+	)
+
+	if a.Filter == nil && blend == blendNormal {
+		// Paint straight onto the destination
+		sh.paint(dst)
+
+		return nil
+	}
+
+	bounds := dst.bounds
+
+	var fx *filterEffect
+
+	if a.Filter != nil {
+		fx = r.filterEffect(a.Filter, ctm, dst.bounds)
+		bounds = fx.bounds
+	}
+
+	l := newLayer(bounds)
+	sh.paint(l)
+
+	if fx != nil {
+		l.clip(fx.region)
+		l.blur(fx.sigma)
+		l.clip(fx.region)
+	}
+
+	dst.composite(l, blend, 1)
+
+	return nil
+}
+
+// shape is a resolved shape, ready for painting
+type shape struct {
+	path        path
+	ctm         matrix
+	fill        paint
+	hasFill     bool
+	stroke      paint
+	hasStroke   bool
+	strokeWidth float64
+	roundCap    bool
+}
+
+// paint paints the fill, and then the stroke, of the shape
+func (s *shape) paint(dst *layer) {
+	// Only rasterize the area the shape (and its stroke) touches
+	var (
+		minP, maxP = bbox(transformed(s.path, s.ctm))
+		margin     = int(math.Ceil(s.strokeWidth*s.ctm.factor()/2)) + 1
+		bounds     = image.Rect(
+			int(math.Floor(minP.x))-margin, int(math.Floor(minP.y))-margin,
+			int(math.Ceil(maxP.x))+margin, int(math.Ceil(maxP.y))+margin,
+		).Intersect(dst.bounds)
+	)
+
+	if s.hasFill {
+		dst.paint(fillCoverage(bounds, s.path, s.ctm), s.fill, blendNormal)
+	}
+
+	if s.hasStroke && s.strokeWidth > 0 {
+		dst.paint(
+			strokeCoverage(bounds, s.path, s.ctm, s.strokeWidth, s.roundCap),
+			s.stroke,
+			blendNormal,
+		)
+	}
+}
+
+// paintOf resolves the scene paint.
+// The flag is false for unset and "none" paints
+func paintOf(p scene.Paint, ctm matrix) (paint, bool, error) {
+	if p.Gradient == nil {
+		if p.Color == "" || p.Color == scene.None.Color {
+			return paint{}, false, nil
+		}
+
+		c, err := parseColor(p.Color)
+		if err != nil {
+			return paint{}, false, err
+		}
+
+		return paint{color: c}, true, nil
+	}
+
+	g := p.Gradient
+	if len(g.Stops) == 0 {
+		return paint{}, false, fmt.Errorf("gradient %q has no stops", g.ID)
+	}
+
+	stops := make([]stop, len(g.Stops))
+
+	for i, s := range g.Stops {
+		c, err := parseColor(s.Color)
+		if err != nil {
+			return paint{}, false, err
+		}
+
+		// Offsets never go back, as per the SVG spec
+		offset := min(max(s.Offset, 0), 1)
+		if i > 0 {
+			offset = max(offset, stops[i-1].offset)
+		}
+
+		stops[i] = stop{offset: offset, color: c}
+	}
+
+	return paint{
+		gradient: &gradient{
+			inv:   ctm.invert(),
+			x1:    g.X1,
+			y1:    g.Y1,
+			x2:    g.X2,
+			y2:    g.Y2,
+			stops: stops,
+		},
+	}, true, nil
+}
+
+// filterEffect is a resolved blur filter
+type filterEffect struct {
+	region *image.Alpha    // filter region coverage
+	bounds image.Rectangle // device space bounds the filter is computed in
+	sigma  float64         // device space blur deviation
+}
+
+// filterEffect resolves the blur filter applied to the shape.
+// The filter region is the default 10% around the entire canvas,
+// in the user space of the shape
+func (r *renderer) filterEffect(f *scene.Filter, ctm matrix, bounds image.Rectangle) *filterEffect {
+	var (
+		rp = rectPath(-0.1*r.viewBox, -0.1*r.viewBox, 1.2*r.viewBox, 1.2*r.viewBox, 0, 0)
+
+		deviceSigma = f.Blur * ctm.factor()
+		margin      = int(math.Ceil(3*deviceSigma)) + 2
+	)
+
+	// Only compute what can bleed into the destination
+	minP, maxP := bbox(transformed(rp, ctm))
+
+	fb := image.Rect(
+		int(math.Floor(minP.x)), int(math.Floor(minP.y)),
+		int(math.Ceil(maxP.x)), int(math.Ceil(maxP.y)),
+	).Intersect(bounds.Inset(-margin))
+
+	return &filterEffect{
+		region: fillCoverage(fb, rp, ctm),
+		bounds: fb,
+		sigma:  deviceSigma,
+	}
+}
+
+// bbox returns the bounding box of the path points (including control points)
+func bbox(p path) (point, point) {
+	minP := point{math.Inf(1), math.Inf(1)}
+	maxP := point{math.Inf(-1), math.Inf(-1)}
+
+	for _, s := range p {
+		n := 1
+		if s.kind == cubeTo {
+			n = 3
+		} else if s.kind == closePath {
+			n = 0
+		}
+
+		for _, pt := range s.pts[:n] {
+			minP.x, minP.y = math.Min(minP.x, pt.x), math.Min(minP.y, pt.y)
+			maxP.x, maxP.y = math.Max(maxP.x, pt.x), math.Max(maxP.y, pt.y)
+		}
+	}
+
+	if math.IsInf(minP.x, 1) {
+		return point{}, point{}
+	}
+
+	return minP, maxP
+}
+
+// transformed returns the path with all points transformed
+func transformed(p path, m matrix) path {
+	out := make(path, len(p))
+
+	for i, s := range p {
+		out[i] = s
+
+		for j := range s.pts {
+			out[i].pts[j] = m.apply(s.pts[j])
+		}
+	}
+
+	return out
+}
+
+// rasterize rasterizes the device space polygons into a coverage mask.
+// The polygons are unioned (nonzero), as long as they share the same orientation
+func rasterize(bounds image.Rectangle, draw func(z *vector.Rasterizer, ox, oy float64)) *image.Alpha {
+	cov := image.NewAlpha(bounds)
+	if bounds.Empty() {
+		return cov
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+
+	draw(z, float64(bounds.Min.X), float64(bounds.Min.Y))
+
+	z.Draw(cov, bounds, image.Opaque, image.Point{})
+
+	return cov
+}
+
+// fillCoverage rasterizes the path fill, transformed to device space
+func fillCoverage(bounds image.Rectangle, p path, m matrix) *image.Alpha {
+	return rasterize(bounds, func(z *vector.Rasterizer, ox, oy float64) {
+		z.DrawOp = draw.Src
+
+		pt := func(p point) (float32, float32) {
+			p = m.apply(p)
+
+			return float32(p.x - ox), float32(p.y - oy)
+		}
+
+		open := false
+
+		for _, s := range p {
+			switch s.kind {
+			case moveTo:
+				if open {
+					z.ClosePath()
+				}
+
+				z.MoveTo(pt(s.pts[0]))
+
+				open = true
+			case lineTo:
+				z.LineTo(pt(s.pts[0]))
+			case cubeTo:
+				bx, by := pt(s.pts[0])
+				cx, cy := pt(s.pts[1])
+				dx, dy := pt(s.pts[2])
+
+				z.CubeTo(bx, by, cx, cy, dx, dy)
+			case closePath:
+				if open {
+					z.ClosePath()
+				}
+
+				open = false
+			}
+		}
+
+		if open {
+			z.ClosePath()
+		}
+	})
+}
+
+// strokeCoverage rasterizes the path stroke, transformed to device space.
+// Joins are always round, and caps are either round or butt
+func strokeCoverage(bounds image.Rectangle, p path, m matrix, width float64, roundCap bool) *image.Alpha {
+	var (
+		hw            = width * m.factor() / 2
+		lines, closed = flatten(p, m)
+	)
+
+	return rasterize(bounds, func(z *vector.Rasterizer, ox, oy float64) {
+		z.DrawOp = draw.Src
+
+		polygon := func(pts ...point) {
+			z.MoveTo(float32(pts[0].x-ox), float32(pts[0].y-oy))
+
+			for _, pt := range pts[1:] {
+				z.LineTo(float32(pt.x-ox), float32(pt.y-oy))
+			}
+
+			z.ClosePath()
+		}
+
+		disc := func(c point) {
+			// Same orientation as the segment quads below
+			n := int(math.Min(math.Max(math.Ceil(hw*2), 8), 64))
+			pts := make([]point, n)
+
+			for i := range pts {
+				sin, cos := math.Sincos(-2 * math.Pi * float64(i) / float64(n))
+				pts[i] = point{c.x + hw*cos, c.y + hw*sin}
+			}
+
+			polygon(pts...)
+		}
+
+		for li, line := range lines {
+			for i := 1; i < len(line); i++ {
+				a, b := line[i-1], line[i]
+
+				l := dist(a, b)
+				if l == 0 {
+					continue
+				}
+
+				nx, ny := -(b.y-a.y)/l*hw, (b.x-a.x)/l*hw
+
+				polygon(
+					point{a.x + nx, a.y + ny},
+					point{b.x + nx, b.y + ny},
+					point{b.x - nx, b.y - ny},
+					point{a.x - nx, a.y - ny},
+				)
+
+				// Round join
+				if i < len(line)-1 || closed[li] {
+					disc(b)
+				}
+			}
+
+			if roundCap && !closed[li] {
+				disc(line[0])
+				disc(line[len(line)-1])
+			}
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package raster
+
+import (
+	"sync"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// textFont is the font text is rasterized in, whatever the requested font family.
+// Go Bold covers the Latin, Greek and Cyrillic scripts: other glyphs are skipped
+var textFont = sync.OnceValues(func() (*sfnt.Font, error) {
+	return sfnt.Parse(gobold.TTF)
+})
+
+// textPath lays the text out on a single line, centered on its anchor point,
+// and returns its glyph outlines
+func textPath(t *scene.Text) (path, error) {
+	f, err := textFont()
+	if err != nil {
+		return nil, err
+	}
+
+	// Glyphs are loaded in font units, and scaled down to the font size
+	var (
+		b    sfnt.Buffer
+		upem = fixed.I(int(f.UnitsPerEm()))
+		k    = t.FontSize / float64(f.UnitsPerEm())
+	)
+
+	type glyph struct {
+		index sfnt.GlyphIndex
+		x     float64 // the pen position, in font units
+	}
+
+	var (
+		glyphs []glyph
+		pen    float64
+	)
+
+	for _, r := range t.Content {
+		index, err := f.GlyphIndex(&b, r)
+		if err != nil || index == 0 {
+			continue
+		}
+
+		if len(glyphs) > 0 {
+			// Fonts without kerning tables report an error
+			if kern, err := f.Kern(&b, glyphs[len(glyphs)-1].index, index, upem, font.HintingNone); err == nil {
+				pen += fromFixed(kern)
+			}
+		}
+
+		advance, err := f.GlyphAdvance(&b, index, upem, font.HintingNone)
+		if err != nil {
+			return nil, err
+		}
+
+		glyphs = append(glyphs, glyph{index: index, x: pen})
+		pen += fromFixed(advance)
+	}
+
+	metrics, err := f.Metrics(&b, upem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+
+	// The text is centered horizontally (text-anchor="middle"),
+	// and on the middle of the em box vertically (dominant-baseline="central")
+	var (
+		originX = t.X - pen*k/2
+		originY = t.Y + (fromFixed(metrics.Ascent)-fromFixed(metrics.Descent))*k/2
+		p       path
+	)
+
+	for _, g := range glyphs {
+		segments, err := f.LoadGlyph(&b, g.index, upem, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		at := func(v fixed.Point26_6) point {
+			return point{
+				originX + (g.x+fromFixed(v.X))*k,
+				originY + fromFixed(v.Y)*k,
+			}
+		}
+
+		var current point
+
+		for i, s := range segments {
+			switch s.Op {
+			case sfnt.SegmentOpMoveTo:
+				if i > 0 {
+					p.close()
+				}
+
+				current = at(s.Args[0])
+				p.moveTo(current)
+			case sfnt.SegmentOpLineTo:
+				current = at(s.Args[0])
+				p.lineTo(current)
+			case sfnt.SegmentOpQuadTo:
+				// Elevate the quadratic curve to a cubic one
+				c, end := at(s.Args[0]), at(s.Args[1])
+
+				p.cubeTo(
+					point{current.x + 2*(c.x-current.x)/3, current.y + 2*(c.y-current.y)/3},
+					point{end.x + 2*(c.x-end.x)/3, end.y + 2*(c.y-end.y)/3},
+					end,
+				)
+
+				current = end
+			case sfnt.SegmentOpCubeTo:
+				current = at(s.Args[2])
+				p.cubeTo(at(s.Args[0]), at(s.Args[1]), current)
+			}
+		}
+
+		if len(segments) > 0 {
+			p.close()
+		}
+	}
+
+	return p, nil
+}
+
+// fromFixed converts the 26.6 fixed-point value to a float
+func fromFixed(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
@@ -0,0 +1,170 @@
+package avatars
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+// Generator draws the content of an avatar style.
+// The content is masked into the avatar shape by the caller
+type Generator interface {
+	// ViewBox returns the side of the square canvas the style draws on, in user units
+	ViewBox() int
+
+	// Draw deterministically draws the avatar content for the seed
+	// (derived from the name), using the non-empty palette
+	Draw(seed int, palette Palette) []scene.Node
+}
+
+// builtin is one of the built-in avatar styles
+type builtin struct {
+	draw     func(id int, palette Palette) []scene.Node
+	backdrop func(id int, palette Palette) string // the color at the center
+	animate  func(id int, palette Palette, nodes []scene.Node) []scene.Node
+	viewBox  int
+	maskType scene.MaskType
+
+	// The number of leading drawn nodes making up the full-bleed background
+	background int
+}
+
+func (b builtin) ViewBox() int {
+	return b.viewBox
+}
+
+func (b builtin) Draw(seed int, palette Palette) []scene.Node {
+	return b.draw(seed, palette)
+}
+
+func (b builtin) Backdrop(seed int, palette Palette) string {
+	return b.backdrop(seed, palette)
+}
+
+func (b builtin) Animate(seed int, palette Palette, nodes []scene.Node) []scene.Node {
+	return b.animate(seed, palette, nodes)
+}
+
+func (b builtin) Background() int {
+	return b.background
+}
+
+// marbleGenerator is the default style, which unknown styles fall back to
+var marbleGenerator = builtin{
+	draw:       drawMarble,
+	backdrop:   marbleBackdrop,
+	animate:    animateMarble,
+	viewBox:    marbleSize,
+	background: 1,
+}
+
+// registry holds the available avatar styles
+var registry = struct {
+	sync.RWMutex
+
+	styles map[Style]Generator
+	order  []Style // registration order
+}{
+	styles: make(map[Style]Generator),
+}
+
+func init() {
+	Register(Beam, builtin{
+		draw:       drawBeam,
+		backdrop:   beamBackdrop,
+		animate:    animateBeam,
+		viewBox:    beamSize,
+		background: 1,
+	})
+	Register(Bauhaus, builtin{
+		draw:       drawBauhaus,
+		backdrop:   bauhausBackdrop,
+		animate:    animateBauhaus,
+		viewBox:    bauhausSize,
+		background: 1,
+	})
+	Register(Marble, marbleGenerator)
+	Register(Pixel, builtin{
+		draw:     drawPixel,
+		backdrop: pixelBackdrop,
+		animate:  animatePixel,
+		viewBox:  pixelSize,
+		maskType: scene.MaskAlpha,
+	})
+	Register(Ring, builtin{
+		draw:       drawRing,
+		backdrop:   ringBackdrop,
+		animate:    animateRing,
+		viewBox:    ringSize,
+		background: 2,
+	})
+	Register(Sunset, builtin{
+		draw:     drawSunset,
+		backdrop: sunsetBackdrop,
+		animate:  animateSunset,
+		viewBox:  sunsetSize,
+	})
+}
+
+// Register makes the avatar style available to Render, Generate and the server.
+// Style names should be lowercase, since the server matches them case-insensitively.
+// Like the standard library registries, it panics if the style is empty,
+// the generator is nil, or the style is already registered
+func Register(style Style, gen Generator) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if style == "" {
+		panic("avatars: Register style is empty")
+	}
+
+	if gen == nil {
+		panic(fmt.Sprintf("avatars: Register generator is nil for style %q", style))
+	}
+
+	if _, exists := registry.styles[style]; exists {
+		panic(fmt.Sprintf("avatars: Register called twice for style %q", style))
+	}
+
+	registry.styles[style] = gen
+	registry.order = append(registry.order, style)
+}
+
+// unregister removes the avatar style, so tests can clean up after Register
+func unregister(style Style) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	delete(registry.styles, style)
+	registry.order = slices.DeleteFunc(registry.order, func(s Style) bool {
+		return s == style
+	})
+}
+
+// Styles returns the available avatar styles,
+// with the built-in ones first, in registration order
+func Styles() []Style {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return append([]Style(nil), registry.order...)
+}
+
+// lookup returns the generator for the style, if it's registered
+func lookup(style Style) (Generator, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	gen, ok := registry.styles[style]
+
+	return gen, ok
+}
+
+// ValidStyle checks if the style is a registered avatar style
+func ValidStyle(style Style) bool {
+	_, ok := lookup(style)
+
+	return ok
+}
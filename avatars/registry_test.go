@@ -0,0 +1,78 @@
+package avatars
+
+import (
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripes is a custom style, drawing vertical palette stripes
+type stripes struct{}
+
+func (stripes) ViewBox() int {
+	return 10
+}
+
+func (stripes) Draw(seed int, palette Palette) []scene.Node {
+	nodes := make([]scene.Node, 0, 10)
+
+	for i := range 10 {
+		nodes = append(nodes, &scene.Rect{
+			X:      float64(i),
+			Width:  1,
+			Height: 10,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(palette[(seed+i)%len(palette)]),
+			},
+		})
+	}
+
+	return nodes
+}
+
+// TestRegister isn't parallel, so the style it registers never leaks
+// into the other tests, which only resume once it's unregistered
+func TestRegister(t *testing.T) {
+	const style Style = "stripes"
+
+	Register(style, stripes{})
+	t.Cleanup(func() {
+		unregister(style)
+	})
+
+	t.Run("listed and valid", func(t *testing.T) {
+		styles := Styles()
+
+		assert.Equal(t, []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset}, styles[:6])
+		assert.Contains(t, styles, style)
+		assert.True(t, ValidStyle(style))
+	})
+
+	t.Run("rendered", func(t *testing.T) {
+		svg, err := Render(Options{Style: style, Name: "Amelia Earhart", Palette: Palette{"#219EBC"}})
+		require.NoError(t, err)
+
+		assert.Contains(t, svg, `<svg viewBox="0 0 10 10"`)
+		assert.Contains(t, svg, `<mask id="mask_stripes_`)
+		assert.Contains(t, svg, `<rect x="9" width="1" height="10" fill="#219EBC"/>`)
+		assert.Equal(t, svg, Generate(style, "Amelia Earhart", Palette{"#219EBC"}, 0, false))
+	})
+
+	t.Run("duplicate style", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register(Beam, stripes{})
+		})
+	})
+
+	t.Run("invalid registration", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register("", stripes{})
+		})
+
+		assert.Panics(t, func() {
+			Register("nil", nil)
+		})
+	})
+}
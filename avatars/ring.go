@@ -1,8 +1,9 @@
 package avatars
 
 import (
-	"fmt"
-	"strings"
+	"io"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
 )
 
 const (
@@ -20,7 +21,7 @@ func buildRingColors(id int, palette Palette) Palette {
 
 	shuffle := make([]string, ringShuffle)
 	for i := 0; i < ringShuffle; i++ {
-		shuffle[i] = palette[(id+i)%len(palette)]
+		shuffle[i] = pickColor(palette, id, i)
 	}
 
 	return []string{
@@ -36,85 +37,60 @@ func buildRingColors(id int, palette Palette) Palette {
 	}
 }
 
-// GenerateRing returns the ring-style avatar SVG
-func GenerateRing(name string, palette Palette, size int, square bool) string {
-	var (
-		id     = NameToID(name)
-		colors = buildRingColors(id, palette)
-		maskID = fmt.Sprintf("mask_ring_%d", id)
-	)
+// ringPaths are the ring shapes, each painted with the matching ring color.
+// The center circle is painted with the last color
+var ringPaths = []string{
+	// Two halves
+	"M0 0h90v45H0z",
+	"M0 45h90v45H0z",
 
-	// Start building out the SVG
-	var b strings.Builder
+	// Three concentric rings (2 paths each)
+	"M83 45a38 38 0 00-76 0h76z",
+	"M83 45a38 38 0 01-76 0h76z",
+	"M77 45a32 32 0 10-64 0h64z",
+	"M77 45a32 32 0 11-64 0h64z",
+	"M71 45a26 26 0 00-52 0h52z",
+	"M71 45a26 26 0 01-52 0h52z",
+}
 
-	if size > 0 {
-		// Custom size
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`,
-			ringSize, ringSize,
-			size, size,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg">`,
-			ringSize, ringSize,
-		)
-	}
+// ringBackdrop returns the center circle color
+func ringBackdrop(id int, palette Palette) string {
+	return buildRingColors(id, palette)[8]
+}
 
-	// Mask group
-	_, _ = fmt.Fprintf(
-		&b,
-		`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">`,
-		maskID,
-		ringSize, ringSize,
+// drawRing draws the ring-style avatar content
+func drawRing(id int, palette Palette) []scene.Node {
+	var (
+		colors = buildRingColors(id, palette)
+		nodes  = make([]scene.Node, 0, len(colors))
 	)
 
-	if square {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" fill="#FFFFFF"/>`,
-			ringSize, ringSize,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" rx="%d" fill="#FFFFFF"/>`,
-			ringSize, ringSize,
-			ringSize*2,
-		)
+	for i, d := range ringPaths {
+		nodes = append(nodes, &scene.Path{
+			D: d,
+			Attrs: scene.Attrs{
+				Fill: scene.Color(colors[i]),
+			},
+		})
 	}
 
-	b.WriteString(`</mask>`)
-
-	// Masked group
-	_, _ = fmt.Fprintf(&b, `<g mask="url(#%s)">`, maskID)
-
-	// Two halves
-	_, _ = fmt.Fprintf(&b, `<path d="M0 0h90v45H0z" fill="%s"/>`, colors[0])
-	_, _ = fmt.Fprintf(&b, `<path d="M0 45h90v45H0z" fill="%s"/>`, colors[1])
-
-	// Three concentric rings (2 paths each)
-	_, _ = fmt.Fprintf(&b, `<path d="M83 45a38 38 0 00-76 0h76z" fill="%s"/>`, colors[2])
-	_, _ = fmt.Fprintf(&b, `<path d="M83 45a38 38 0 01-76 0h76z" fill="%s"/>`, colors[3])
-
-	_, _ = fmt.Fprintf(&b, `<path d="M77 45a32 32 0 10-64 0h64z" fill="%s"/>`, colors[4])
-	_, _ = fmt.Fprintf(&b, `<path d="M77 45a32 32 0 11-64 0h64z" fill="%s"/>`, colors[5])
-
-	_, _ = fmt.Fprintf(&b, `<path d="M71 45a26 26 0 00-52 0h52z" fill="%s"/>`, colors[6])
-	_, _ = fmt.Fprintf(&b, `<path d="M71 45a26 26 0 01-52 0h52z" fill="%s"/>`, colors[7])
-
 	// Center circle
-	_, _ = fmt.Fprintf(&b, `<circle cx="45" cy="45" r="23" fill="%s"/>`, colors[8])
-
-	// Group closure
-	b.WriteString(`</g>`)
+	return append(nodes, &scene.Circle{
+		CX: ringSize / 2,
+		CY: ringSize / 2,
+		R:  23,
+		Attrs: scene.Attrs{
+			Fill: scene.Color(colors[8]),
+		},
+	})
+}
 
-	// Final svg closure
-	b.WriteString(`</svg>`)
+// WriteRing streams the ring-style avatar SVG to the given writer
+func WriteRing(w io.Writer, name string, palette Palette, size int, square bool) error {
+	return write(w, Ring, name, palette, size, square)
+}
 
-	return b.String()
+// GenerateRing returns the ring-style avatar SVG
+func GenerateRing(name string, palette Palette, size int, square bool) string {
+	return generate(Ring, name, palette, size, square)
 }
@@ -0,0 +1,319 @@
+// Package scene defines the structured avatar model: a small scene graph
+// of shapes, paths, transforms, masks and filters. Avatar styles draw into
+// a scene, which encoders (such as the SVG one) then serialize
+package scene
+
+// Scene is a complete avatar drawing, on a square canvas
+type Scene struct {
+	// The side of the square canvas (viewBox), in user units
+	ViewBox float64
+
+	// The rendered width and height, in px.
+	// If 0, the encoders pick their own default
+	Size int
+
+	// The drawing, in painting order
+	Children []Node
+
+	// The accessible title and description of the drawing, if any
+	Title, Desc *Label
+}
+
+// Label is a text alternative of the drawing, announced by screen readers
+type Label struct {
+	// The unique ID of the label element
+	ID string
+
+	// The label text
+	Text string
+}
+
+// Node is a single element in the scene graph
+type Node interface {
+	node()
+}
+
+// Group is a group of nodes, sharing a transform and an optional mask
+type Group struct {
+	// The mask applied to the group, if any
+	Mask *Mask
+
+	// The group transform
+	Transform Transform
+
+	// The looping animation of the group, if any
+	Animation *Animation
+
+	// The grouped nodes, in painting order
+	Children []Node
+}
+
+// Animation is a looping keyframes animation of a group.
+// The animated transform replaces the group transform, so animated groups
+// usually just wrap the moving nodes. Animations are purely decorative:
+// viewers preferring reduced motion, and raster encoders, get the static drawing
+type Animation struct {
+	// The unique ID of the animation, naming both its keyframes and its CSS class
+	ID string
+
+	// The duration of a single iteration, in seconds
+	Duration float64
+
+	// The animation delay, in seconds. Negative delays start the animation midway
+	Delay float64
+
+	// Flag indicating if every other iteration should run backwards
+	Alternate bool
+
+	// Flag indicating if the animation should run at a constant pace,
+	// instead of easing in and out
+	Linear bool
+
+	// The CSS transform origin, relative to the group's bounding box.
+	// Defaults to its center
+	Origin string
+
+	// The animation keyframes, ordered by offset
+	Keyframes []Keyframe
+}
+
+// Keyframe is the state of an animated group at a point of the animation.
+// Unset properties are interpolated from the neighboring keyframes,
+// and match the static drawing at the start and end of the animation
+type Keyframe struct {
+	// The keyframe position in the iteration, in [0, 1]
+	Offset float64
+
+	// The group transform, around the animation origin.
+	// Rotations ignore their center as a result
+	Transform Transform
+
+	// The group transparency, in [0, 1]. 0 (opaque) is unset, and 1 is invisible
+	Fade float64
+}
+
+// Rect is an (optionally rounded) rectangle
+type Rect struct {
+	Attrs
+
+	X, Y          float64
+	Width, Height float64
+	RX            float64 // corner radius
+}
+
+// Circle is a circle
+type Circle struct {
+	Attrs
+
+	CX, CY float64
+	R      float64
+}
+
+// Line is a straight line, which is only ever stroked
+type Line struct {
+	Attrs
+
+	X1, Y1 float64
+	X2, Y2 float64
+}
+
+// Path is an arbitrary shape
+type Path struct {
+	Attrs
+
+	// The SVG path data
+	D string
+}
+
+// Text is a single line of text, centered on its anchor point
+type Text struct {
+	Attrs
+
+	X, Y float64 // the anchor point, in the middle of the text
+
+	FontFamily string  // the CSS font family list
+	FontSize   float64 // in user units
+	FontWeight int     // the CSS font weight, such as 400 (normal) or 700 (bold). 0 is normal
+
+	// The text content
+	Content string
+}
+
+func (*Group) node()  {}
+func (*Rect) node()   {}
+func (*Circle) node() {}
+func (*Line) node()   {}
+func (*Path) node()   {}
+func (*Text) node()   {}
+
+// BlendMode is a CSS mix-blend-mode
+type BlendMode string
+
+const (
+	BlendNormal   BlendMode = ""
+	BlendMultiply BlendMode = "multiply"
+	BlendScreen   BlendMode = "screen"
+	BlendOverlay  BlendMode = "overlay"
+)
+
+// LineCap is the shape of stroke ends
+type LineCap string
+
+const (
+	CapButt  LineCap = ""
+	CapRound LineCap = "round"
+)
+
+// Attrs are the presentation attributes shared by all shapes
+type Attrs struct {
+	// The filter applied to the shape, if any
+	Filter *Filter
+
+	// The shape transform
+	Transform Transform
+
+	// The fill paint. Unset fills inherit from the scene,
+	// which doesn't fill anything by default
+	Fill Paint
+
+	// The stroke paint
+	Stroke Paint
+
+	// The stroke width. If 0, it's left to the encoder default (1)
+	StrokeWidth float64
+
+	// The shape of the stroke ends
+	LineCap LineCap
+
+	// The blend mode the shape is composited with
+	BlendMode BlendMode
+
+	// The order of the encoded attributes, by name (such as "fill" or "d"),
+	// for shapes that must keep the byte-identical output of earlier versions.
+	// Listed attributes come first, and are written even if zero.
+	// The others follow, in the encoder order
+	Order []string
+}
+
+// Paint is either a solid color, or a gradient
+type Paint struct {
+	// The gradient, which takes precedence over the color
+	Gradient *LinearGradient
+
+	// The #RRGGBB hex color, or "none"
+	Color string
+
+	// The #RRGGBB hex color for viewers preferring a dark color scheme, if it differs.
+	// Gradients have their own dark stop colors
+	Dark string
+}
+
+// None is the paint that doesn't paint anything
+var None = Paint{Color: "none"}
+
+// Color returns a solid color paint
+func Color(hex string) Paint {
+	return Paint{Color: hex}
+}
+
+// Gradient returns a gradient paint
+func Gradient(g *LinearGradient) Paint {
+	return Paint{Gradient: g}
+}
+
+// IsZero checks if the paint is unset
+func (p Paint) IsZero() bool {
+	return p.Gradient == nil && p.Color == ""
+}
+
+// MaskType is the channel a mask is derived from
+type MaskType string
+
+const (
+	// MaskLuminance masks show the white areas of their content
+	MaskLuminance MaskType = ""
+
+	// MaskAlpha masks show the opaque areas of their content
+	MaskAlpha MaskType = "alpha"
+)
+
+// Mask hides the group content outside of the mask content
+type Mask struct {
+	// The unique ID of the mask
+	ID string
+
+	// The mask type. Defaults to a luminance mask
+	Type MaskType
+
+	// The mask content
+	Children []Node
+}
+
+// Filter is a Gaussian blur filter effect
+type Filter struct {
+	// The unique ID of the filter
+	ID string
+
+	// The blur standard deviation, in user units
+	Blur float64
+}
+
+// LinearGradient is a linear gradient, in user space
+type LinearGradient struct {
+	// The unique ID of the gradient
+	ID string
+
+	// The gradient vector
+	X1, Y1 float64
+	X2, Y2 float64
+
+	// The color stops, ordered by offset
+	Stops []Stop
+}
+
+// Stop is a single gradient color stop
+type Stop struct {
+	// The #RRGGBB hex color
+	Color string
+
+	// The #RRGGBB hex color for viewers preferring a dark color scheme, if it differs
+	Dark string
+
+	// The stop position on the gradient vector, in [0, 1]
+	Offset float64
+}
+
+// Transform is a list of transform operations, applied right to left
+// (like the SVG transform attribute)
+type Transform []Op
+
+// Op is a single transform operation
+type Op interface {
+	op()
+}
+
+// Translate moves by (X, Y)
+type Translate struct {
+	X, Y float64
+}
+
+// Rotate rotates by Angle degrees, around (CX, CY)
+type Rotate struct {
+	Angle  float64
+	CX, CY float64
+}
+
+// Scale scales uniformly by the factor
+type Scale struct {
+	Factor float64
+}
+
+// ScaleXY scales by X horizontally, and by Y vertically
+type ScaleXY struct {
+	X, Y float64
+}
+
+func (Translate) op() {}
+func (Rotate) op()    {}
+func (Scale) op()     {}
+func (ScaleXY) op()   {}
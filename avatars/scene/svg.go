@@ -0,0 +1,644 @@
+package scene
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encoder serializes scenes into a specific format
+type Encoder interface {
+	// Encode writes the encoded scene to w
+	Encode(w io.Writer, s *Scene) error
+}
+
+// SVGEncoder encodes scenes as SVG documents
+type SVGEncoder struct{}
+
+// Encode writes the scene out as an SVG document
+func (SVGEncoder) Encode(w io.Writer, s *Scene) error {
+	return WriteSVG(w, s)
+}
+
+// WriteSVG writes the scene out as an SVG document.
+// The output is buffered, so w receives it in a few large writes
+func WriteSVG(w io.Writer, s *Scene) error {
+	e := &svgWriter{
+		b: bufio.NewWriter(w),
+	}
+
+	e.raw(`<svg viewBox="0 0 `, num(s.ViewBox), ` `, num(s.ViewBox), `" fill="none" role="img"`)
+	e.raw(` xmlns="http://www.w3.org/2000/svg"`)
+
+	if s.Title != nil {
+		e.attr("aria-labelledby", s.Title.ID)
+	}
+
+	if s.Desc != nil {
+		e.attr("aria-describedby", s.Desc.ID)
+	}
+
+	if s.Size > 0 {
+		e.raw(` width="`, strconv.Itoa(s.Size), `" height="`, strconv.Itoa(s.Size), `"`)
+	}
+
+	e.raw(`>`)
+
+	// The labels come first, so they're the elements' accessible names
+	e.label("title", s.Title)
+	e.label("desc", s.Desc)
+
+	e.content(s)
+	e.raw(`</svg>`)
+
+	return e.b.Flush()
+}
+
+// Symbol is a single scene of a sprite sheet
+type Symbol struct {
+	// The unique ID the symbol is referenced by, as in <use href="#id">
+	ID string
+
+	// The symbol scene. Its size is ignored, as symbols scale to the <use> element.
+	// Its mask, filter and gradient IDs must be unique within the sprite sheet,
+	// see Scene.PrefixIDs
+	Scene *Scene
+}
+
+// WriteSprite writes the scenes out as an SVG sprite sheet,
+// holding a <symbol> element per scene, along with its labels.
+// The output is buffered, so w receives it in a few large writes
+func WriteSprite(w io.Writer, symbols []Symbol) error {
+	e := &svgWriter{
+		b: bufio.NewWriter(w),
+	}
+
+	e.raw(`<svg xmlns="http://www.w3.org/2000/svg">`)
+
+	for _, sym := range symbols {
+		e.raw(`<symbol`)
+		e.attr("id", sym.ID)
+		e.raw(` viewBox="0 0 `, num(sym.Scene.ViewBox), ` `, num(sym.Scene.ViewBox), `" fill="none">`)
+
+		e.label("title", sym.Scene.Title)
+		e.label("desc", sym.Scene.Desc)
+
+		// Each symbol holds its own definitions
+		e.seen, e.defs, e.anims, e.darks = nil, nil, nil, nil
+
+		e.content(sym.Scene)
+		e.raw(`</symbol>`)
+	}
+
+	e.raw(`</svg>`)
+
+	return e.b.Flush()
+}
+
+// svgWriter serializes scene nodes.
+// Write errors are sticky in the buffered writer, and surface on flush
+type svgWriter struct {
+	b     *bufio.Writer
+	seen  map[any]struct{}
+	defs  []any // *Filter or *LinearGradient
+	anims []*Animation
+	darks []darkRule
+
+	// The attributes of the open shape element, collected
+	// so they can be written out in the shape order
+	shape   *Attrs
+	pending []attribute
+}
+
+// attribute is a single element attribute, with its escaped value
+type attribute struct {
+	name  string
+	value string
+}
+
+// darkRule is a CSS rule, swapping a paint property for a dark color scheme
+type darkRule struct {
+	class    string
+	property string
+	color    string
+}
+
+// raw writes out the strings as-is
+func (e *svgWriter) raw(parts ...string) {
+	for _, p := range parts {
+		_, _ = e.b.WriteString(p)
+	}
+}
+
+// attr writes out a single attribute, escaping its value.
+// The attributes of an open shape element are collected instead, see flush
+func (e *svgWriter) attr(name, value string) {
+	if e.shape != nil {
+		e.pending = append(e.pending, attribute{name: name, value: escape(value)})
+
+		return
+	}
+
+	e.raw(` `, name, `="`, escape(value), `"`)
+}
+
+// listed checks if the attribute is in the order of the open shape element,
+// in which case it's written even if zero
+func (e *svgWriter) listed(name string) bool {
+	return e.shape != nil && slices.Contains(e.shape.Order, name)
+}
+
+// ref registers a shared definition, and returns its url(#id) reference
+func (e *svgWriter) ref(def any, id string) string {
+	if e.seen == nil {
+		e.seen = make(map[any]struct{})
+	}
+
+	if _, ok := e.seen[def]; !ok {
+		e.seen[def] = struct{}{}
+		e.defs = append(e.defs, def)
+	}
+
+	return "url(#" + id + ")"
+}
+
+// content writes out the scene drawing, followed by its definitions and styles
+func (e *svgWriter) content(s *Scene) {
+	e.nodes(s.ViewBox, s.Children)
+
+	// Shared definitions, in the order they were first referenced
+	if len(e.defs) > 0 {
+		e.raw(`<defs>`)
+
+		for _, d := range e.defs {
+			switch d := d.(type) {
+			case *Filter:
+				e.filter(d)
+			case *LinearGradient:
+				e.gradient(d)
+			}
+		}
+
+		e.raw(`</defs>`)
+	}
+
+	e.styles()
+}
+
+func (e *svgWriter) nodes(viewBox float64, nodes []Node) {
+	for _, n := range nodes {
+		e.node(viewBox, n)
+	}
+}
+
+func (e *svgWriter) node(viewBox float64, n Node) {
+	switch n := n.(type) {
+	case *Group:
+		// Masks are written right before the group they apply to
+		if n.Mask != nil {
+			e.raw(`<mask`)
+			e.attr("id", n.Mask.ID)
+
+			if n.Mask.Type != MaskLuminance {
+				e.attr("mask-type", string(n.Mask.Type))
+			}
+
+			e.raw(` maskUnits="userSpaceOnUse" x="0" y="0"`)
+			e.attr("width", num(viewBox))
+			e.attr("height", num(viewBox))
+			e.raw(`>`)
+			e.nodes(viewBox, n.Mask.Children)
+			e.raw(`</mask>`)
+		}
+
+		e.raw(`<g`)
+
+		if n.Mask != nil {
+			e.attr("mask", "url(#"+n.Mask.ID+")")
+		}
+
+		if len(n.Transform) > 0 {
+			e.attr("transform", n.Transform.String())
+		}
+
+		if n.Animation != nil {
+			e.animate(n.Animation)
+		}
+
+		e.raw(`>`)
+		e.nodes(viewBox, n.Children)
+		e.raw(`</g>`)
+	case *Rect:
+		e.open("rect", &n.Attrs)
+
+		if n.X != 0 || e.listed("x") {
+			e.attr("x", num(n.X))
+		}
+
+		if n.Y != 0 || e.listed("y") {
+			e.attr("y", num(n.Y))
+		}
+
+		e.attr("width", num(n.Width))
+		e.attr("height", num(n.Height))
+
+		if n.RX != 0 || e.listed("rx") {
+			e.attr("rx", num(n.RX))
+		}
+
+		e.close(&n.Attrs)
+	case *Circle:
+		e.open("circle", &n.Attrs)
+		e.attr("cx", num(n.CX))
+		e.attr("cy", num(n.CY))
+		e.attr("r", num(n.R))
+		e.close(&n.Attrs)
+	case *Line:
+		e.open("line", &n.Attrs)
+		e.attr("x1", num(n.X1))
+		e.attr("y1", num(n.Y1))
+		e.attr("x2", num(n.X2))
+		e.attr("y2", num(n.Y2))
+		e.close(&n.Attrs)
+	case *Path:
+		e.open("path", &n.Attrs)
+		e.attr("d", n.D)
+		e.close(&n.Attrs)
+	case *Text:
+		e.open("text", &n.Attrs)
+		e.attr("x", num(n.X))
+		e.attr("y", num(n.Y))
+
+		if n.FontFamily != "" {
+			e.attr("font-family", n.FontFamily)
+		}
+
+		e.attr("font-size", num(n.FontSize))
+
+		if n.FontWeight != 0 {
+			e.attr("font-weight", strconv.Itoa(n.FontWeight))
+		}
+
+		e.attr("text-anchor", "middle")
+		e.attr("dominant-baseline", "central")
+		e.presentation(&n.Attrs)
+		e.flush()
+		e.raw(`>`, escape(n.Content), `</text>`)
+	}
+}
+
+// open opens the shape element, starting with the attributes that precede the geometry.
+// The attributes are collected until the element is flushed
+func (e *svgWriter) open(name string, a *Attrs) {
+	e.raw(`<`, name)
+	e.shape, e.pending = a, e.pending[:0]
+
+	if a.Filter != nil {
+		e.attr("filter", e.ref(a.Filter, a.Filter.ID))
+	}
+
+	if a.BlendMode != BlendNormal {
+		e.attr("style", "mix-blend-mode:"+string(a.BlendMode))
+	}
+}
+
+// close adds the attributes that follow the geometry, and closes the shape element
+func (e *svgWriter) close(a *Attrs) {
+	e.presentation(a)
+	e.flush()
+	e.raw(`/>`)
+}
+
+// flush writes out the collected attributes of the open shape element:
+// the ones in the shape order first, followed by the others
+func (e *svgWriter) flush() {
+	defer func() {
+		e.shape = nil
+	}()
+
+	pending := e.pending
+	if len(e.shape.Order) == 0 {
+		for _, a := range pending {
+			e.raw(` `, a.name, `="`, a.value, `"`)
+		}
+
+		return
+	}
+
+	written := make([]bool, len(pending))
+
+	for _, name := range e.shape.Order {
+		for i, a := range pending {
+			if !written[i] && a.name == name {
+				e.raw(` `, a.name, `="`, a.value, `"`)
+				written[i] = true
+			}
+		}
+	}
+
+	for i, a := range pending {
+		if !written[i] {
+			e.raw(` `, a.name, `="`, a.value, `"`)
+		}
+	}
+}
+
+// presentation writes out the paint and transform attributes
+func (e *svgWriter) presentation(a *Attrs) {
+	if a.StrokeWidth != 0 {
+		e.attr("stroke-width", num(a.StrokeWidth))
+	}
+
+	if !a.Stroke.IsZero() {
+		e.attr("stroke", e.paint(a.Stroke))
+	}
+
+	if !a.Fill.IsZero() {
+		e.attr("fill", e.paint(a.Fill))
+	}
+
+	if a.LineCap != CapButt {
+		e.attr("stroke-linecap", string(a.LineCap))
+	}
+
+	if len(a.Transform) > 0 {
+		e.attr("transform", a.Transform.String())
+	}
+
+	// Dark colors swap solid paints through CSS classes
+	var classes []string
+
+	if a.Stroke.Gradient == nil && a.Stroke.Dark != "" {
+		classes = append(classes, e.darkClass("stroke", a.Stroke.Dark))
+	}
+
+	if a.Fill.Gradient == nil && a.Fill.Dark != "" {
+		classes = append(classes, e.darkClass("fill", a.Fill.Dark))
+	}
+
+	if len(classes) > 0 {
+		e.attr("class", strings.Join(classes, " "))
+	}
+}
+
+// paint returns the paint attribute value
+func (e *svgWriter) paint(p Paint) string {
+	if p.Gradient != nil {
+		return e.ref(p.Gradient, p.Gradient.ID)
+	}
+
+	return p.Color
+}
+
+// label writes out the title or desc element, if the label is set
+func (e *svgWriter) label(name string, l *Label) {
+	if l == nil {
+		return
+	}
+
+	e.raw(`<`, name)
+	e.attr("id", l.ID)
+	e.raw(`>`, escape(l.Text), `</`, name, `>`)
+}
+
+// animate registers the animation, and writes out the class attribute that applies it
+func (e *svgWriter) animate(a *Animation) {
+	if e.seen == nil {
+		e.seen = make(map[any]struct{})
+	}
+
+	if _, ok := e.seen[a]; !ok {
+		e.seen[a] = struct{}{}
+		e.anims = append(e.anims, a)
+	}
+
+	e.attr("class", a.ID)
+}
+
+// darkClass registers the dark color of the paint property, and returns the class applying it.
+// Classes are named after the property and color, so the rules of several scenes
+// in the same document never conflict
+func (e *svgWriter) darkClass(property, color string) string {
+	class := "dark-" + property + "-" + strings.TrimPrefix(color, "#")
+
+	if e.seen == nil {
+		e.seen = make(map[any]struct{})
+	}
+
+	if _, ok := e.seen[class]; !ok {
+		e.seen[class] = struct{}{}
+		e.darks = append(e.darks, darkRule{class: class, property: property, color: color})
+	}
+
+	return class
+}
+
+// styles writes out the CSS of the animations and dark colors, if any
+func (e *svgWriter) styles() {
+	if len(e.anims) == 0 && len(e.darks) == 0 {
+		return
+	}
+
+	e.raw(`<style>`)
+	e.animations()
+	e.darkColors()
+	e.raw(`</style>`)
+}
+
+// darkColors writes out the CSS rules of the dark colors, in the order they were first used.
+// They only apply to viewers preferring a dark color scheme
+func (e *svgWriter) darkColors() {
+	if len(e.darks) == 0 {
+		return
+	}
+
+	e.raw(`@media (prefers-color-scheme:dark){`)
+
+	for _, r := range e.darks {
+		e.raw(`.`, cssIdent(r.class), `{`, r.property, `:`, escape(r.color), `}`)
+	}
+
+	e.raw(`}`)
+}
+
+// animations writes out the CSS keyframes and classes of the animations, in the order
+// they were first used. They are all disabled for viewers preferring reduced motion
+func (e *svgWriter) animations() {
+	if len(e.anims) == 0 {
+		return
+	}
+
+	selectors := make([]string, 0, len(e.anims))
+
+	for _, a := range e.anims {
+		name := cssIdent(a.ID)
+		selectors = append(selectors, "."+name)
+
+		e.raw(`@keyframes `, name, `{`)
+
+		for _, k := range a.Keyframes {
+			decls := make([]string, 0, 2)
+
+			if len(k.Transform) > 0 {
+				decls = append(decls, "transform:"+k.Transform.css())
+			}
+
+			if k.Fade != 0 {
+				decls = append(decls, "opacity:"+num(round(1-k.Fade)))
+			}
+
+			e.raw(num(round(k.Offset*100)), `%{`, strings.Join(decls, ";"), `}`)
+		}
+
+		timing := "ease-in-out"
+		if a.Linear {
+			timing = "linear"
+		}
+
+		e.raw(`}.`, name, `{animation:`, name, ` `, num(a.Duration), `s `, timing)
+
+		if a.Delay != 0 {
+			e.raw(` `, num(a.Delay), `s`)
+		}
+
+		e.raw(` infinite`)
+
+		if a.Alternate {
+			e.raw(` alternate`)
+		}
+
+		origin := a.Origin
+		if origin == "" {
+			origin = "center"
+		}
+
+		e.raw(`;transform-box:fill-box;transform-origin:`, escape(origin), `}`)
+	}
+
+	e.raw(`@media (prefers-reduced-motion:reduce){`, strings.Join(selectors, ","), `{animation:none}}`)
+}
+
+func (e *svgWriter) filter(f *Filter) {
+	e.raw(`<filter`)
+	e.attr("id", f.ID)
+	e.raw(` filterUnits="userSpaceOnUse" color-interpolation-filters="sRGB">`)
+	e.raw(`<feFlood flood-opacity="0" result="BackgroundImageFix"/>`)
+	e.raw(`<feBlend in="SourceGraphic" in2="BackgroundImageFix" result="shape"/>`)
+	e.raw(`<feGaussianBlur stdDeviation="`, num(f.Blur), `" result="effect1_foregroundBlur"/>`)
+	e.raw(`</filter>`)
+}
+
+func (e *svgWriter) gradient(g *LinearGradient) {
+	e.raw(`<linearGradient`)
+	e.attr("id", g.ID)
+	e.attr("x1", num(g.X1))
+	e.attr("y1", num(g.Y1))
+	e.attr("x2", num(g.X2))
+	e.attr("y2", num(g.Y2))
+	e.raw(` gradientUnits="userSpaceOnUse">`)
+
+	for _, s := range g.Stops {
+		e.raw(`<stop`)
+
+		if s.Offset != 0 {
+			e.attr("offset", num(s.Offset))
+		}
+
+		e.attr("stop-color", s.Color)
+
+		if s.Dark != "" {
+			e.attr("class", e.darkClass("stop-color", s.Dark))
+		}
+
+		e.raw(`/>`)
+	}
+
+	e.raw(`</linearGradient>`)
+}
+
+// String returns the SVG transform attribute value
+func (t Transform) String() string {
+	parts := make([]string, 0, len(t))
+
+	for _, op := range t {
+		switch op := op.(type) {
+		case Translate:
+			parts = append(parts, fmt.Sprintf("translate(%.2f %.2f)", op.X, op.Y))
+		case Rotate:
+			parts = append(parts, "rotate("+num(op.Angle)+" "+num(op.CX)+" "+num(op.CY)+")")
+		case Scale:
+			parts = append(parts, fmt.Sprintf("scale(%.2f)", op.Factor))
+		case ScaleXY:
+			parts = append(parts, fmt.Sprintf("scale(%.2f %.2f)", op.X, op.Y))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// css returns the CSS transform property value.
+// Rotations are around the transform origin, so their center is dropped
+func (t Transform) css() string {
+	parts := make([]string, 0, len(t))
+
+	for _, op := range t {
+		switch op := op.(type) {
+		case Translate:
+			parts = append(parts, "translate("+num(round(op.X))+"px,"+num(round(op.Y))+"px)")
+		case Rotate:
+			parts = append(parts, "rotate("+num(round(op.Angle))+"deg)")
+		case Scale:
+			parts = append(parts, "scale("+num(round(op.Factor))+")")
+		case ScaleXY:
+			parts = append(parts, "scale("+num(round(op.X))+","+num(round(op.Y))+")")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// num formats the number in its shortest form
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// round rounds the number to 2 decimals, dropping floating point noise
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// cssIdent escapes the ID for use as a CSS identifier, such as a class name.
+// Anything besides ASCII letters, digits, dashes and underscores is hex-escaped
+func cssIdent(id string) string {
+	var b strings.Builder
+
+	for _, r := range id {
+		if r == '-' || r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+
+			continue
+		}
+
+		_, _ = fmt.Fprintf(&b, `\%x `, r)
+	}
+
+	return b.String()
+}
+
+var attrEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	`"`, "&#34;",
+)
+
+// escape escapes the attribute value, or text content
+func escape(v string) string {
+	return attrEscaper.Replace(v)
+}
@@ -0,0 +1,363 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encode encodes the scene as SVG, failing the test on error
+func encode(t *testing.T, s *Scene) string {
+	t.Helper()
+
+	var b strings.Builder
+
+	require.NoError(t, SVGEncoder{}.Encode(&b, s))
+
+	return b.String()
+}
+
+func TestWriteSVG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shared definitions", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			blur = &Filter{ID: "blur", Blur: 7}
+			grad = &LinearGradient{
+				ID: "grad",
+				X2: 10,
+				Stops: []Stop{
+					{Color: "#000000"},
+					{Color: "#FFFFFF", Offset: 0.5},
+				},
+			}
+		)
+
+		s := &Scene{
+			ViewBox: 10,
+			Size:    40,
+			Children: []Node{
+				&Circle{CX: 5, CY: 5, R: 2.5, Attrs: Attrs{Filter: blur, Fill: Gradient(grad)}},
+				&Rect{Width: 10, Height: 10, Attrs: Attrs{Filter: blur, Stroke: Gradient(grad)}},
+			},
+		}
+
+		out := encode(t, s)
+
+		assert.True(t, strings.HasPrefix(out, `<svg viewBox="0 0 10 10" fill="none" role="img"`+
+			` xmlns="http://www.w3.org/2000/svg" width="40" height="40">`))
+		assert.Contains(t, out, `<circle filter="url(#blur)" cx="5" cy="5" r="2.5" fill="url(#grad)"/>`)
+		assert.Contains(t, out, `<stop stop-color="#000000"/><stop offset="0.5" stop-color="#FFFFFF"/>`)
+
+		// Each definition is written once, in the order of first use
+		assert.Equal(t, 1, strings.Count(out, `<filter id="blur"`))
+		assert.Equal(t, 1, strings.Count(out, `<linearGradient id="grad"`))
+		assert.Less(t, strings.Index(out, `<filter`), strings.Index(out, `<linearGradient`))
+	})
+
+	t.Run("masked group", func(t *testing.T) {
+		t.Parallel()
+
+		s := &Scene{
+			ViewBox: 36,
+			Children: []Node{
+				&Group{
+					Mask: &Mask{
+						ID:       "m",
+						Type:     MaskAlpha,
+						Children: []Node{&Rect{Width: 36, Height: 36, RX: 72, Attrs: Attrs{Fill: Color("#FFFFFF")}}},
+					},
+					Transform: Transform{Translate{X: 1, Y: 2.5}, Rotate{Angle: 90, CX: 18, CY: 18}, Scale{Factor: 1.2}},
+					Children:  []Node{&Line{X2: 36, Attrs: Attrs{StrokeWidth: 2, Stroke: Color("#000000")}}},
+				},
+			},
+		}
+
+		assert.Contains(
+			t,
+			encode(t, s),
+			`<mask id="m" mask-type="alpha" maskUnits="userSpaceOnUse" x="0" y="0" width="36" height="36">`+
+				`<rect width="36" height="36" rx="72" fill="#FFFFFF"/></mask>`+
+				`<g mask="url(#m)" transform="translate(1.00 2.50) rotate(90 18 18) scale(1.20)">`+
+				`<line x1="0" y1="0" x2="36" y2="0" stroke-width="2" stroke="#000000"/></g>`,
+		)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+
+		s := &Scene{
+			ViewBox: 80,
+			Children: []Node{
+				&Text{
+					X:          40,
+					Y:          40,
+					FontFamily: "Helvetica, sans-serif",
+					FontSize:   32,
+					FontWeight: 600,
+					Content:    "A&B",
+					Attrs:      Attrs{Fill: Color("#FFFFFF")},
+				},
+				&Text{X: 10, Y: 5.5, FontSize: 4, Content: "<"},
+			},
+		}
+
+		out := encode(t, s)
+
+		assert.Contains(
+			t,
+			out,
+			`<text x="40" y="40" font-family="Helvetica, sans-serif" font-size="32" font-weight="600"`+
+				` text-anchor="middle" dominant-baseline="central" fill="#FFFFFF">A&amp;B</text>`,
+		)
+		assert.Contains(
+			t,
+			out,
+			`<text x="10" y="5.5" font-size="4" text-anchor="middle" dominant-baseline="central">&lt;</text>`,
+		)
+	})
+
+	t.Run("animations", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			spin = &Animation{
+				ID:       "spin.1",
+				Duration: 12,
+				Linear:   true,
+				Keyframes: []Keyframe{
+					{Offset: 1, Transform: Transform{Rotate{Angle: -360, CX: 5, CY: 5}}},
+				},
+			}
+			blink = &Animation{
+				ID:        "blink",
+				Duration:  4.5,
+				Delay:     -2,
+				Alternate: true,
+				Origin:    "bottom",
+				Keyframes: []Keyframe{
+					{Offset: 0.92, Transform: Transform{Translate{X: 1, Y: -2}, ScaleXY{X: 1, Y: 0.1}}},
+					{Offset: 0.96, Fade: 0.7},
+				},
+			}
+		)
+
+		s := &Scene{
+			ViewBox: 10,
+			Children: []Node{
+				&Group{Animation: spin, Children: []Node{&Rect{Width: 5, Height: 5}}},
+				&Group{Animation: blink, Children: []Node{&Rect{Width: 2, Height: 2}}},
+				&Group{Animation: spin, Children: []Node{&Rect{Width: 1, Height: 1}}},
+			},
+		}
+
+		out := encode(t, s)
+
+		assert.Contains(t, out, `<g class="spin.1"><rect width="5" height="5"/></g>`)
+		assert.Contains(t, out, `<g class="blink"><rect width="2" height="2"/></g>`)
+
+		// Each animation is written once, in the order of first use
+		assert.True(t, strings.HasSuffix(out, `<style>`+
+			`@keyframes spin\2e 1{100%{transform:rotate(-360deg)}}`+
+			`.spin\2e 1{animation:spin\2e 1 12s linear infinite;transform-box:fill-box;transform-origin:center}`+
+			`@keyframes blink{92%{transform:translate(1px,-2px) scale(1,0.1)}96%{opacity:0.3}}`+
+			`.blink{animation:blink 4.5s ease-in-out -2s infinite alternate;`+
+			`transform-box:fill-box;transform-origin:bottom}`+
+			`@media (prefers-reduced-motion:reduce){.spin\2e 1,.blink{animation:none}}`+
+			`</style></svg>`), out)
+	})
+
+	t.Run("escaped attributes", func(t *testing.T) {
+		t.Parallel()
+
+		s := &Scene{
+			ViewBox:  10,
+			Children: []Node{&Path{D: `M0 0"/><script/>`}},
+		}
+
+		assert.Contains(t, encode(t, s), `<path d="M0 0&#34;/&gt;&lt;script/&gt;"/>`)
+	})
+
+	t.Run("attribute order", func(t *testing.T) {
+		t.Parallel()
+
+		s := &Scene{
+			ViewBox: 10,
+			Children: []Node{
+				&Rect{Width: 10, Height: 10, RX: 2, Attrs: Attrs{Fill: Color("#000000")}},
+				&Rect{
+					Width:  10,
+					Height: 10,
+					RX:     2,
+					Attrs: Attrs{
+						Fill:      Color("#000000"),
+						Transform: Transform{Scale{Factor: 2}},
+						Order:     []string{"x", "y", "width", "height", "transform", "fill", "rx"},
+					},
+				},
+				&Path{D: "M0 0h10v10H0z", Attrs: Attrs{Fill: Color("#FFFFFF"), Order: []string{"fill"}}},
+			},
+		}
+
+		out := encode(t, s)
+
+		assert.Contains(t, out, `<rect width="10" height="10" rx="2" fill="#000000"/>`)
+		assert.Contains(
+			t,
+			out,
+			`<rect x="0" y="0" width="10" height="10" transform="scale(2.00)" fill="#000000" rx="2"/>`,
+		)
+		assert.Contains(t, out, `<path fill="#FFFFFF" d="M0 0h10v10H0z"/>`)
+	})
+}
+
+func TestWriteSprite(t *testing.T) {
+	t.Parallel()
+
+	newScene := func() *Scene {
+		blur := &Filter{ID: "blur", Blur: 7}
+
+		return &Scene{
+			ViewBox: 10,
+			Size:    40,
+			Children: []Node{
+				&Group{
+					Mask:     &Mask{ID: "m", Children: []Node{&Rect{Width: 10, Height: 10}}},
+					Children: []Node{&Circle{R: 5, Attrs: Attrs{Filter: blur}}},
+				},
+			},
+		}
+	}
+
+	a, b := newScene(), newScene()
+
+	a.PrefixIDs("a_")
+	b.PrefixIDs("b_")
+
+	var out strings.Builder
+
+	require.NoError(t, WriteSprite(&out, []Symbol{{ID: "avatar-a", Scene: a}, {ID: "avatar-b", Scene: b}}))
+
+	assert.Equal(
+		t,
+		`<svg xmlns="http://www.w3.org/2000/svg">`+
+			`<symbol id="avatar-a" viewBox="0 0 10 10" fill="none">`+
+			`<mask id="a_m" maskUnits="userSpaceOnUse" x="0" y="0" width="10" height="10">`+
+			`<rect width="10" height="10"/></mask>`+
+			`<g mask="url(#a_m)"><circle filter="url(#a_blur)" cx="0" cy="0" r="5"/></g>`+
+			`<defs><filter id="a_blur"`,
+		out.String()[:strings.Index(out.String(), ` filterUnits`)],
+	)
+
+	// Each symbol holds its own, prefixed definitions
+	assert.Equal(t, 1, strings.Count(out.String(), `<filter id="a_blur"`))
+	assert.Equal(t, 1, strings.Count(out.String(), `<filter id="b_blur"`))
+	assert.Contains(t, out.String(), `<symbol id="avatar-b" viewBox="0 0 10 10" fill="none"><mask id="b_m"`)
+	assert.True(t, strings.HasSuffix(out.String(), `</defs></symbol></svg>`))
+
+	// The symbols keep their labels
+	a, b = newScene(), newScene()
+	a.Title = &Label{ID: "title", Text: "Grace"}
+	a.Desc = &Label{ID: "desc", Text: "A marble avatar"}
+	b.Title = &Label{ID: "title", Text: "Ada"}
+
+	a.PrefixIDs("a_")
+	b.PrefixIDs("b_")
+
+	out.Reset()
+
+	require.NoError(t, WriteSprite(&out, []Symbol{{ID: "avatar-a", Scene: a}, {ID: "avatar-b", Scene: b}}))
+
+	assert.Contains(
+		t,
+		out.String(),
+		`<symbol id="avatar-a" viewBox="0 0 10 10" fill="none">`+
+			`<title id="a_title">Grace</title><desc id="a_desc">A marble avatar</desc><mask id="a_m"`,
+	)
+	assert.Contains(
+		t,
+		out.String(),
+		`<symbol id="avatar-b" viewBox="0 0 10 10" fill="none"><title id="b_title">Ada</title><mask id="b_m"`,
+	)
+}
+
+func TestRecolor(t *testing.T) {
+	t.Parallel()
+
+	var (
+		grad = &LinearGradient{ID: "grad", Stops: []Stop{{Color: "#000000"}}}
+		mask = &Rect{Attrs: Attrs{Fill: Color("#FFFFFF")}}
+		s    = &Scene{
+			Children: []Node{
+				&Group{
+					Mask: &Mask{ID: "m", Children: []Node{mask}},
+					Children: []Node{
+						&Rect{Attrs: Attrs{Fill: Gradient(grad), Stroke: None}},
+						&Path{Attrs: Attrs{Fill: Gradient(grad), Stroke: Color("#FFFFFF")}},
+					},
+				},
+			},
+		}
+	)
+
+	calls := 0
+
+	s.Recolor(func(string) string {
+		calls++
+
+		return "#FF0000"
+	})
+
+	// The shared gradient is recolored once, while
+	// "none" paints and the mask content are untouched
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "#FF0000", grad.Stops[0].Color)
+	assert.Equal(t, "#FFFFFF", mask.Fill.Color)
+}
+
+func TestSetDarkColors(t *testing.T) {
+	t.Parallel()
+
+	draw := func(colors ...string) *Scene {
+		return &Scene{
+			Children: []Node{
+				&Group{
+					Mask: &Mask{ID: "m", Children: []Node{&Rect{Attrs: Attrs{Fill: Color("#FFFFFF")}}}},
+					Children: []Node{
+						&Rect{Attrs: Attrs{Fill: Color(colors[0]), Stroke: None}},
+						&Path{Attrs: Attrs{Fill: Gradient(&LinearGradient{ID: "g", Stops: []Stop{{Color: colors[1]}}})}},
+						&Line{Attrs: Attrs{Stroke: Color(colors[2])}},
+					},
+				},
+			},
+		}
+	}
+
+	s := draw("#000000", "#111111", "#222222")
+	s.SetDarkColors(draw("#FFFFFF", "#EEEEEE", "#222222"))
+
+	var (
+		group = s.Children[0].(*Group)
+		rect  = group.Children[0].(*Rect)
+		path  = group.Children[1].(*Path)
+		line  = group.Children[2].(*Line)
+	)
+
+	// Only differing colors get a dark one, and the mask is untouched
+	assert.Equal(t, "#FFFFFF", rect.Fill.Dark)
+	assert.Empty(t, rect.Stroke.Dark)
+	assert.Equal(t, "#EEEEEE", path.Fill.Gradient.Stops[0].Dark)
+	assert.Empty(t, line.Stroke.Dark)
+	assert.Empty(t, group.Mask.Children[0].(*Rect).Fill.Dark)
+
+	out := encode(t, s)
+
+	assert.Contains(t, out, `<rect width="0" height="0" stroke="none" fill="#000000" class="dark-fill-FFFFFF"/>`)
+	assert.Contains(t, out, `<stop stop-color="#111111" class="dark-stop-color-EEEEEE"/>`)
+	assert.Contains(t, out, `<style>@media (prefers-color-scheme:dark){`+
+		`.dark-fill-FFFFFF{fill:#FFFFFF}.dark-stop-color-EEEEEE{stop-color:#EEEEEE}}</style>`)
+}
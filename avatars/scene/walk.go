@@ -0,0 +1,178 @@
+package scene
+
+// Walk calls fn for every node in the scene drawing, depth first
+// and in painting order. Mask content is not part of the drawing,
+// so it's not visited
+func (s *Scene) Walk(fn func(n Node)) {
+	walk(s.Children, fn)
+}
+
+func walk(nodes []Node, fn func(n Node)) {
+	for _, n := range nodes {
+		fn(n)
+
+		if g, ok := n.(*Group); ok {
+			walk(g.Children, fn)
+		}
+	}
+}
+
+// AttrsOf returns the presentation attributes of the node, if it has any
+func AttrsOf(n Node) *Attrs {
+	switch n := n.(type) {
+	case *Rect:
+		return &n.Attrs
+	case *Circle:
+		return &n.Attrs
+	case *Line:
+		return &n.Attrs
+	case *Path:
+		return &n.Attrs
+	case *Text:
+		return &n.Attrs
+	default:
+		return nil
+	}
+}
+
+// Recolor replaces every fill, stroke and gradient stop color
+// in the drawing (dark colors included) with the color returned by fn
+func (s *Scene) Recolor(fn func(color string) string) {
+	var (
+		seen  = make(map[*LinearGradient]struct{})
+		paint = func(p *Paint) {
+			if p.Gradient == nil {
+				if p.Color != "" && p.Color != None.Color {
+					p.Color = fn(p.Color)
+				}
+
+				if p.Dark != "" {
+					p.Dark = fn(p.Dark)
+				}
+
+				return
+			}
+
+			if _, ok := seen[p.Gradient]; ok {
+				return
+			}
+
+			seen[p.Gradient] = struct{}{}
+
+			for i := range p.Gradient.Stops {
+				stop := &p.Gradient.Stops[i]
+				stop.Color = fn(stop.Color)
+
+				if stop.Dark != "" {
+					stop.Dark = fn(stop.Dark)
+				}
+			}
+		}
+	)
+
+	s.Walk(func(n Node) {
+		if a := AttrsOf(n); a != nil {
+			paint(&a.Fill)
+			paint(&a.Stroke)
+		}
+	})
+}
+
+// SetDarkColors sets the dark colors of the drawing from its dark variant:
+// the same drawing, in the colors for viewers preferring a dark color scheme.
+// The shapes of both drawings are paired in painting order
+func (s *Scene) SetDarkColors(dark *Scene) {
+	var light, shaded []*Attrs
+
+	s.Walk(func(n Node) {
+		if a := AttrsOf(n); a != nil {
+			light = append(light, a)
+		}
+	})
+
+	dark.Walk(func(n Node) {
+		if a := AttrsOf(n); a != nil {
+			shaded = append(shaded, a)
+		}
+	})
+
+	paint := func(p *Paint, dark Paint) {
+		if p.Gradient == nil || dark.Gradient == nil {
+			if p.Color != None.Color && dark.Color != p.Color {
+				p.Dark = dark.Color
+			}
+
+			return
+		}
+
+		for i := range min(len(p.Gradient.Stops), len(dark.Gradient.Stops)) {
+			if stop := &p.Gradient.Stops[i]; dark.Gradient.Stops[i].Color != stop.Color {
+				stop.Dark = dark.Gradient.Stops[i].Color
+			}
+		}
+	}
+
+	for i := range min(len(light), len(shaded)) {
+		paint(&light[i].Fill, shaded[i].Fill)
+		paint(&light[i].Stroke, shaded[i].Stroke)
+	}
+}
+
+// PrefixIDs prefixes the IDs of every mask, filter, gradient, animation and label in the scene,
+// so several scenes can be embedded in one document without their IDs colliding
+func (s *Scene) PrefixIDs(prefix string) {
+	var (
+		seen   = make(map[any]struct{})
+		rename = func(def any, id *string) {
+			if _, ok := seen[def]; ok {
+				return
+			}
+
+			seen[def] = struct{}{}
+			*id = prefix + *id
+		}
+		visit func(nodes []Node)
+	)
+
+	visit = func(nodes []Node) {
+		for _, n := range nodes {
+			if g, ok := n.(*Group); ok {
+				if g.Mask != nil {
+					rename(g.Mask, &g.Mask.ID)
+					visit(g.Mask.Children)
+				}
+
+				if g.Animation != nil {
+					rename(g.Animation, &g.Animation.ID)
+				}
+
+				visit(g.Children)
+
+				continue
+			}
+
+			a := AttrsOf(n)
+			if a == nil {
+				continue
+			}
+
+			if a.Filter != nil {
+				rename(a.Filter, &a.Filter.ID)
+			}
+
+			for _, p := range []Paint{a.Fill, a.Stroke} {
+				if p.Gradient != nil {
+					rename(p.Gradient, &p.Gradient.ID)
+				}
+			}
+		}
+	}
+
+	visit(s.Children)
+
+	for _, l := range []*Label{s.Title, s.Desc} {
+		if l != nil {
+			rename(l, &l.ID)
+		}
+	}
+}
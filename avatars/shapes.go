@@ -0,0 +1,174 @@
+package avatars
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+var ErrInvalidShape = errors.New(
+	"invalid shape, expected circle, square, rounded, squircle or hexagon, and a radius in [0, 0.5]",
+)
+
+// Shape is the outline the avatar is masked into
+type Shape string
+
+const (
+	ShapeCircle   Shape = "circle"
+	ShapeSquare   Shape = "square"
+	ShapeRounded  Shape = "rounded" // a square with rounded corners, see Options.Radius
+	ShapeSquircle Shape = "squircle"
+	ShapeHexagon  Shape = "hexagon"
+)
+
+// squircleHandle is the length of the squircle curve handles, relative to its side.
+// The shorter the handles, the closer the squircle is to a square
+const squircleHandle = 0.06
+
+// ValidShape checks if the shape is supported
+func ValidShape(shape Shape) bool {
+	switch shape {
+	case ShapeCircle, ShapeSquare, ShapeRounded, ShapeSquircle, ShapeHexagon:
+		return true
+	default:
+		return false
+	}
+}
+
+// outline is the avatar outline: its shape,
+// and the corner radius of rounded shapes (relative to the avatar size)
+type outline struct {
+	shape  Shape
+	radius float64
+}
+
+// squareOutline returns the outline of the square flag: a square or a circle
+func squareOutline(square bool) outline {
+	if square {
+		return outline{shape: ShapeSquare}
+	}
+
+	return outline{shape: ShapeCircle}
+}
+
+// outline returns the avatar outline. The shape takes precedence over the square flag,
+// and a radius alone implies a rounded shape
+func (o Options) outline() outline {
+	switch {
+	case o.Shape == ShapeRounded:
+		return outline{shape: ShapeRounded, radius: o.Radius}
+	case o.Shape != "":
+		return outline{shape: o.Shape}
+	case o.Square:
+		return outline{shape: ShapeSquare}
+	case o.Radius > 0:
+		return outline{shape: ShapeRounded, radius: o.Radius}
+	default:
+		return outline{shape: ShapeCircle}
+	}
+}
+
+// node returns the outline shape, fitted into the square box
+// of the given side, offset by (offset, offset) in a viewBox of the given size
+func (o outline) node(viewBox, offset, side float64) scene.Node {
+	switch o.shape {
+	case ShapeSquircle:
+		var (
+			k = squircleHandle * side
+			c = side / 2
+		)
+
+		return o.path(offset,
+			"M", 0.0, c,
+			"C", 0.0, k, k, 0.0, c, 0.0,
+			"S", side, k, side, c,
+			"S", side-k, side, c, side,
+			"S", 0.0, side-k, 0.0, c,
+			"Z",
+		)
+	case ShapeHexagon:
+		var (
+			c = side / 2
+			a = side * math.Sqrt(3) / 4 // half the width of the (pointy-top) hexagon
+		)
+
+		return o.path(offset,
+			"M", c, 0.0,
+			"L", c+a, side/4,
+			"L", c+a, side*3/4,
+			"L", c, side,
+			"L", c-a, side*3/4,
+			"L", c-a, side/4,
+			"Z",
+		)
+	}
+
+	rect := &scene.Rect{
+		X:      offset,
+		Y:      offset,
+		Width:  side,
+		Height: side,
+	}
+
+	switch o.shape {
+	case ShapeCircle:
+		rect.RX = side * 2 // clamped to a circle
+	case ShapeRounded:
+		// Inset outlines keep the same corner center
+		rect.RX = round2(max(o.radius*viewBox-offset, 0))
+	}
+
+	return rect
+}
+
+// path returns the path of the commands (strings) and their coordinates (float64s),
+// which are relative to the outline box at (offset, offset)
+func (outline) path(offset float64, parts ...any) *scene.Path {
+	var (
+		d     []byte
+		first = true
+	)
+
+	for _, p := range parts {
+		switch p := p.(type) {
+		case string:
+			d = append(d, p...)
+			first = true
+		case float64:
+			if !first {
+				d = append(d, ' ')
+			}
+
+			d = strconv.AppendFloat(d, round2(offset+p), 'f', -1, 64)
+			first = false
+		default:
+			panic(fmt.Sprintf("unexpected path part %T", p))
+		}
+	}
+
+	return &scene.Path{D: string(d)}
+}
+
+// corner returns how far the outline reaches into the bottom right corner of the viewBox,
+// along the diagonal. Badges are placed on the outline there (mirrored for the other corners)
+func (o outline) corner(viewBox float64) float64 {
+	switch o.shape {
+	case ShapeSquare:
+		return viewBox
+	case ShapeRounded:
+		r := o.radius * viewBox
+
+		return viewBox - r*(1-math.Sqrt2/2)
+	case ShapeSquircle:
+		// The midpoint of the corner curve
+		return viewBox - (0.375*squircleHandle*viewBox + 0.125*viewBox/2)
+	case ShapeHexagon:
+		// The lower right edge, from (c + a, 3/4) to (c, 1)
+		return viewBox/2 + viewBox/2*math.Sqrt(3)/(math.Sqrt(3)+1)
+	default:
+		return viewBox/2 + viewBox/2*math.Sqrt2/2
+	}
+}
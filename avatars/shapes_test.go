@@ -0,0 +1,152 @@
+package avatars
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions_Shape(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid shape", func(t *testing.T) {
+		t.Parallel()
+
+		for _, opts := range []Options{
+			{Shape: "star"},
+			{Shape: ShapeRounded, Radius: -0.1},
+			{Radius: 0.6},
+			{Radius: math.NaN()},
+		} {
+			_, err := Render(opts)
+
+			assert.ErrorIs(t, err, ErrInvalidShape, opts)
+		}
+	})
+
+	t.Run("masks", func(t *testing.T) {
+		t.Parallel()
+
+		testTable := []struct {
+			name     string
+			opts     Options
+			expected string
+		}{
+			{
+				"circle",
+				Options{Shape: ShapeCircle},
+				`<rect width="36" height="36" rx="72" fill="#FFFFFF"/>`,
+			},
+			{
+				"square",
+				Options{Shape: ShapeSquare},
+				`<rect width="36" height="36" fill="#FFFFFF"/>`,
+			},
+			{
+				"square flag",
+				Options{Square: true},
+				`<rect width="36" height="36" fill="#FFFFFF"/>`,
+			},
+			{
+				"shape over square flag",
+				Options{Square: true, Shape: ShapeCircle},
+				`<rect width="36" height="36" rx="72" fill="#FFFFFF"/>`,
+			},
+			{
+				"rounded",
+				Options{Shape: ShapeRounded, Radius: 0.25},
+				`<rect width="36" height="36" rx="9" fill="#FFFFFF"/>`,
+			},
+			{
+				"radius alone",
+				Options{Radius: 0.25},
+				`<rect width="36" height="36" rx="9" fill="#FFFFFF"/>`,
+			},
+			{
+				"squircle",
+				Options{Shape: ShapeSquircle},
+				`<path d="M0 18C0 2.16 2.16 0 18 0S36 2.16 36 18S33.84 36 18 36S0 33.84 0 18Z" fill="#FFFFFF"/>`,
+			},
+			{
+				"hexagon",
+				Options{Shape: ShapeHexagon},
+				`<path d="M18 0L33.59 9L33.59 27L18 36L2.41 27L2.41 9Z" fill="#FFFFFF"/>`,
+			},
+		}
+
+		for _, testCase := range testTable {
+			t.Run(testCase.name, func(t *testing.T) {
+				t.Parallel()
+
+				opts := testCase.opts
+				opts.Style, opts.Name = Beam, "Grace"
+
+				svg, err := Render(opts)
+				require.NoError(t, err)
+
+				assert.Contains(t, svg, `<mask id="mask_beam_69062552"`)
+				assert.Contains(t, svg, testCase.expected)
+			})
+		}
+	})
+
+	t.Run("all styles", func(t *testing.T) {
+		t.Parallel()
+
+		for _, style := range []Style{Beam, Bauhaus, Marble, Pixel, Ring, Sunset} {
+			for _, shape := range []Shape{ShapeRounded, ShapeSquircle, ShapeHexagon} {
+				svg, err := Render(Options{Style: style, Name: "Grace", Shape: shape, Radius: 0.2})
+				require.NoError(t, err)
+
+				round, err := Render(Options{Style: style, Name: "Grace"})
+				require.NoError(t, err)
+
+				assert.NotEqual(t, round, svg, "%s %s", style, shape)
+			}
+		}
+	})
+
+	t.Run("border and badge follow the shape", func(t *testing.T) {
+		t.Parallel()
+
+		svg, err := Render(Options{
+			Style:  Beam,
+			Name:   "Grace",
+			Shape:  ShapeHexagon,
+			Border: &Border{},
+			Badge:  &Badge{Status: StatusOnline},
+		})
+		require.NoError(t, err)
+
+		assert.Contains(
+			t,
+			svg,
+			`<path d="M18 0.9L32.81 9.45L32.81 26.55L18 35.1L3.19 26.55L3.19 9.45Z" stroke-width="1.8"`,
+		)
+
+		// On the lower right edge, rather than tucked into the corner
+		assert.Contains(t, svg, `<circle cx="29.41" cy="29.41" r="4.5" fill="#2ECC71"/>`)
+	})
+
+	t.Run("unique IDs", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			base    = Options{Name: "Grace", UniqueIDs: true}
+			rounded = base
+			hexagon = base
+			square  = base
+		)
+
+		rounded.Shape, rounded.Radius = ShapeRounded, 0.2
+		hexagon.Shape = ShapeHexagon
+		square.Shape = ShapeSquare
+
+		assert.NotEqual(t, base.idPrefix(), rounded.idPrefix())
+		assert.NotEqual(t, base.idPrefix(), hexagon.idPrefix())
+		assert.NotEqual(t, rounded.idPrefix(), hexagon.idPrefix())
+		assert.NotEqual(t, base.idPrefix(), square.idPrefix())
+	})
+}
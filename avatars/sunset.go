@@ -2,7 +2,9 @@ package avatars
 
 import (
 	"fmt"
-	"strings"
+	"io"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
 )
 
 const (
@@ -10,6 +12,9 @@ const (
 	sunsetElements = 4
 )
 
+// sunsetOrder is the attribute order of the halves, as written by earlier versions
+var sunsetOrder = []string{"fill", "d"}
+
 // buildSunsetColors generates the sunset color palette
 func buildSunsetColors(id int, palette Palette) Palette {
 	if len(palette) == 0 {
@@ -18,103 +23,65 @@ func buildSunsetColors(id int, palette Palette) Palette {
 
 	out := make([]string, sunsetElements)
 	for i := 0; i < sunsetElements; i++ {
-		out[i] = palette[(id+i)%len(palette)]
+		out[i] = pickColor(palette, id, i)
 	}
 
 	return out
 }
 
-// GenerateSunset returns the sunset-style avatar SVG
-func GenerateSunset(name string, palette Palette, size int, square bool) string {
+// sunsetBackdrop returns the average of the two gradient colors meeting at the center
+func sunsetBackdrop(id int, palette Palette) string {
+	colors := buildSunsetColors(id, palette)
+
+	return mixColors(colors[1], colors[2])
+}
+
+// drawSunset draws the sunset-style avatar content
+func drawSunset(id int, palette Palette) []scene.Node {
 	var (
-		id     = NameToID(name)
 		colors = buildSunsetColors(id, palette)
-		maskID = fmt.Sprintf("mask_sunset_%d", id)
+		center = float64(sunsetSize / 2)
 	)
 
-	// Start building out the SVG
-	var b strings.Builder
-
-	if size > 0 {
-		// Custom size
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`,
-			sunsetSize, sunsetSize,
-			size, size,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<svg viewBox="0 0 %d %d" fill="none" role="img"`+
-				` xmlns="http://www.w3.org/2000/svg">`,
-			sunsetSize, sunsetSize,
-		)
+	// Two vertical gradients, one per half
+	gradient := func(i int, y1, y2 float64) scene.Paint {
+		return scene.Gradient(&scene.LinearGradient{
+			ID: fmt.Sprintf("gradient_paint%d_linear_%d", i, id),
+			X1: center,
+			Y1: y1,
+			X2: center,
+			Y2: y2,
+			Stops: []scene.Stop{
+				{Color: colors[2*i]},
+				{Color: colors[2*i+1], Offset: 1},
+			},
+		})
 	}
 
-	// Mask group
-	_, _ = fmt.Fprintf(&b,
-		`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">`,
-		maskID,
-		sunsetSize, sunsetSize,
-	)
-
-	if square {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" fill="#FFFFFF"/>`,
-			sunsetSize, sunsetSize,
-		)
-	} else {
-		_, _ = fmt.Fprintf(
-			&b,
-			`<rect width="%d" height="%d" rx="%d" fill="#FFFFFF"/>`,
-			sunsetSize, sunsetSize,
-			sunsetSize*2,
-		)
+	return []scene.Node{
+		&scene.Path{
+			D: "M0 0h80v40H0z",
+			Attrs: scene.Attrs{
+				Fill:  gradient(0, 0, center),
+				Order: sunsetOrder,
+			},
+		},
+		&scene.Path{
+			D: "M0 40h80v40H0z",
+			Attrs: scene.Attrs{
+				Fill:  gradient(1, center, sunsetSize),
+				Order: sunsetOrder,
+			},
+		},
 	}
+}
 
-	b.WriteString(`</mask>`)
-
-	// Masked group
-	_, _ = fmt.Fprintf(&b, `<g mask="url(#%s)">`, maskID)
-
-	// Two half-rectangles filled by vertical gradients
-	_, _ = fmt.Fprintf(
-		&b,
-		`<path fill="url(#gradient_paint0_linear_%d)" d="M0 0h80v40H0z"/>`,
-		id,
-	)
-
-	_, _ = fmt.Fprintf(
-		&b,
-		`<path fill="url(#gradient_paint1_linear_%d)" d="M0 40h80v40H0z"/>`,
-		id,
-	)
-
-	b.WriteString(`</g>`)
-
-	// Two linear gradients
-	_, _ = fmt.Fprintf(&b,
-		`<defs>`+
-			`<linearGradient id="gradient_paint0_linear_%d" x1="%d" y1="0" x2="%d" y2="%d" gradientUnits="userSpaceOnUse">`+
-			`<stop stop-color="%s"/>`+
-			`<stop offset="1" stop-color="%s"/>`+
-			`</linearGradient>`+
-			`<linearGradient id="gradient_paint1_linear_%d" x1="%d" y1="%d" x2="%d" y2="%d" gradientUnits="userSpaceOnUse">`+
-			`<stop stop-color="%s"/>`+
-			`<stop offset="1" stop-color="%s"/>`+
-			`</linearGradient>`+
-			`</defs>`,
-		id, sunsetSize/2, sunsetSize/2, sunsetSize/2, // first gradient
-		colors[0], colors[1],
-		id, sunsetSize/2, sunsetSize/2, sunsetSize/2, sunsetSize, // second gradient
-		colors[2], colors[3],
-	)
-
-	// Final svg closure
-	b.WriteString(`</svg>`)
+// WriteSunset streams the sunset-style avatar SVG to the given writer
+func WriteSunset(w io.Writer, name string, palette Palette, size int, square bool) error {
+	return write(w, Sunset, name, palette, size, square)
+}
 
-	return b.String()
+// GenerateSunset returns the sunset-style avatar SVG
+func GenerateSunset(name string, palette Palette, size int, square bool) string {
+	return generate(Sunset, name, palette, size, square)
 }
@@ -1,6 +1,7 @@
 package avatars
 
 import (
+	"encoding/hex"
 	"strconv"
 	"strings"
 )
@@ -28,11 +29,7 @@ func NameToID(name string) int {
 
 // IDToDigit returns the digit at 10^place in id
 func IDToDigit(id, place int) int {
-	for i := 0; i < place; i++ {
-		id /= 10
-	}
-
-	return id % 10
+	return idToDigit(int64(id), place)
 }
 
 // IDToBoolean returns whether that digit is even
@@ -42,14 +39,46 @@ func IDToBoolean(id, place int) bool {
 
 // IDToPoint returns id%mod, negated when that digit is even and place > 0
 func IDToPoint(id, mod, place int) int {
-	v := id % mod
-	if place > 0 && IDToDigit(id, place)%2 == 0 {
+	return idToPoint(int64(id), mod, place)
+}
+
+// idToDigit is IDToDigit on 64 bits, for ids derived from the seed
+// that would overflow a 32-bit int (such as multiples of it)
+func idToDigit(id int64, place int) int {
+	for i := 0; i < place; i++ {
+		id /= 10
+	}
+
+	return int(id % 10)
+}
+
+// idToPoint is IDToPoint on 64 bits, see idToDigit
+func idToPoint(id int64, mod, place int) int {
+	v := int(id % int64(mod))
+	if place > 0 && idToDigit(id, place)%2 == 0 {
 		return -v
 	}
 
 	return v
 }
 
+// pickColor returns the palette color at id+offset, wrapping around the palette.
+// The sum is computed on 64 bits, so it can't overflow on 32-bit platforms
+func pickColor(palette Palette, id, offset int) string {
+	return palette[(int64(id)+int64(offset))%int64(len(palette))]
+}
+
+// ValidColor checks if the color is a 6-digit hex color (#RRGGBB)
+func ValidColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+
+	_, err := hex.DecodeString(color[1:])
+
+	return err == nil
+}
+
 // Contrast returns a cheap YIQ-based contrast
 func Contrast(hex string) string {
 	// Trim leading #
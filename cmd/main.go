@@ -26,6 +26,8 @@ func main() {
 	cmd.Subcommands = []*ffcli.Command{
 		newServeCmd(),
 		newGenerateCmd(),
+		newStylesCmd(),
+		newRenderCmd(),
 	}
 
 	if err := cmd.ParseAndRun(context.Background(), os.Args[1:]); err != nil {
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"github.com/sig-0/boring-avatars-go/avatars"
+	"github.com/sig-0/boring-avatars-go/avatars/raster"
+)
+
+type renderCfg struct {
+	variant    string
+	size       int
+	colors     string
+	darkColors string
+	palette    string
+	square     bool
+	shape      string
+	radius     float64
+	format     string
+	outputPath string
+	initials   bool
+	animate    bool
+
+	a11y     bool
+	title    string
+	desc     string
+	hideName bool
+
+	border      bool
+	borderWidth float64
+	borderColor string
+	badge       string
+	badgeColor  string
+	badgeCorner string
+	badgeSize   float64
+
+	unmasked    bool
+	transparent bool
+}
+
+// newRenderCmd creates the render command
+func newRenderCmd() *ffcli.Command {
+	cfg := &renderCfg{}
+
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	cfg.registerFlags(fs)
+
+	return &ffcli.Command{
+		Name:       "render",
+		ShortUsage: "render [flags] <name>",
+		LongHelp:   "Renders the avatar of the given name, as an SVG or PNG",
+		FlagSet:    fs,
+		Exec:       cfg.exec,
+	}
+}
+
+// registerFlags registers the render command flags
+func (c *renderCfg) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(
+		&c.variant,
+		"variant",
+		string(avatars.Marble),
+		"the avatar style (see the styles command)",
+	)
+
+	fs.IntVar(
+		&c.size,
+		"size",
+		raster.DefaultSize,
+		"the avatar width and height, in px",
+	)
+
+	fs.StringVar(
+		&c.colors,
+		"colors",
+		"",
+		"the comma-separated hex color palette",
+	)
+
+	fs.StringVar(
+		&c.darkColors,
+		"dark-colors",
+		"",
+		"the comma-separated hex color palette for dark color schemes (SVG only)",
+	)
+
+	fs.StringVar(
+		&c.palette,
+		"palette",
+		"",
+		"the name of a catalog palette, as an alternative to -colors",
+	)
+
+	fs.BoolVar(
+		&c.square,
+		"square",
+		false,
+		"flag indicating if the avatar should be square, instead of round",
+	)
+
+	fs.StringVar(
+		&c.shape,
+		"shape",
+		"",
+		"the avatar shape (circle, square, rounded, squircle, hexagon). Takes precedence over -square",
+	)
+
+	fs.Float64Var(
+		&c.radius,
+		"radius",
+		0,
+		"the corner radius of the rounded shape, relative to the avatar size. Implies -shape rounded if unset",
+	)
+
+	fs.BoolVar(
+		&c.unmasked,
+		"unmasked",
+		false,
+		"flag indicating if the avatar should be left unmasked, full-bleed over its square canvas",
+	)
+
+	fs.BoolVar(
+		&c.transparent,
+		"transparent",
+		false,
+		"flag indicating if the style's background should be left out, for compositing",
+	)
+
+	fs.StringVar(
+		&c.format,
+		"format",
+		"svg",
+		"the output format (svg or png)",
+	)
+
+	fs.StringVar(
+		&c.outputPath,
+		"output-path",
+		"",
+		"the path to write the avatar to. Defaults to stdout",
+	)
+
+	fs.BoolVar(
+		&c.initials,
+		"initials",
+		false,
+		"flag indicating if the name's initials should be drawn on top of the avatar",
+	)
+
+	fs.BoolVar(
+		&c.animate,
+		"animate",
+		false,
+		"flag indicating if the SVG avatar should loop through a subtle motion",
+	)
+
+	fs.BoolVar(
+		&c.a11y,
+		"a11y",
+		false,
+		"flag indicating if the SVG should have an accessible title (the name, by default)",
+	)
+
+	fs.StringVar(
+		&c.title,
+		"title",
+		"",
+		"the accessible SVG title. Implies -a11y",
+	)
+
+	fs.StringVar(
+		&c.desc,
+		"desc",
+		"",
+		"the accessible SVG description. Implies -a11y",
+	)
+
+	fs.BoolVar(
+		&c.hideName,
+		"hide-name",
+		false,
+		"flag indicating if the default title should be a generic one, instead of the name. Implies -a11y",
+	)
+
+	fs.BoolVar(
+		&c.border,
+		"border",
+		false,
+		"flag indicating if the avatar should have an outline border",
+	)
+
+	fs.Float64Var(
+		&c.borderWidth,
+		"border-width",
+		0,
+		"the border width, relative to the avatar size (0.05 by default). Implies -border",
+	)
+
+	fs.StringVar(
+		&c.borderColor,
+		"border-color",
+		"",
+		"the hex border color (white by default). Implies -border",
+	)
+
+	fs.StringVar(
+		&c.badge,
+		"badge",
+		"",
+		"the presence status of the corner badge (online, away, busy, offline)",
+	)
+
+	fs.StringVar(
+		&c.badgeColor,
+		"badge-color",
+		"",
+		"the hex badge color, instead of the status one",
+	)
+
+	fs.StringVar(
+		&c.badgeCorner,
+		"badge-corner",
+		string(avatars.BottomRight),
+		"the badge corner (bottom-right, bottom-left, top-right, top-left)",
+	)
+
+	fs.Float64Var(
+		&c.badgeSize,
+		"badge-size",
+		avatars.DefaultBadgeSize,
+		"the badge diameter, relative to the avatar size",
+	)
+}
+
+// exec executes the render command
+func (c *renderCfg) exec(_ context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected a single name argument")
+	}
+
+	opts := avatars.Options{
+		Style:       avatars.Style(strings.ToLower(c.variant)),
+		Name:        args[0],
+		Size:        c.size,
+		Square:      c.square,
+		Shape:       avatars.Shape(strings.ToLower(c.shape)),
+		Radius:      c.radius,
+		Unmasked:    c.unmasked,
+		Transparent: c.transparent,
+		Initials:    c.initials,
+		Animate:     c.animate,
+	}
+
+	// Resolve the palette
+	switch {
+	case c.colors != "" && c.palette != "":
+		return errors.New("colors and palette are mutually exclusive")
+	case c.colors != "":
+		opts.Palette = parseColors(c.colors)
+	case c.palette != "":
+		palette, ok := avatars.LookupPalette(c.palette)
+		if !ok {
+			return fmt.Errorf("%w: %q", avatars.ErrUnknownPalette, c.palette)
+		}
+
+		opts.Palette = palette
+	}
+
+	if c.darkColors != "" {
+		opts.DarkPalette = parseColors(c.darkColors)
+	}
+
+	if c.border || c.borderWidth != 0 || c.borderColor != "" {
+		opts.Border = &avatars.Border{
+			Width: c.borderWidth,
+		}
+
+		if c.borderColor != "" {
+			opts.Border.Color = "#" + strings.TrimPrefix(c.borderColor, "#")
+		}
+	}
+
+	if c.badge != "" || c.badgeColor != "" {
+		opts.Badge = &avatars.Badge{
+			Status: avatars.Status(strings.ToLower(c.badge)),
+			Corner: avatars.Corner(strings.ToLower(c.badgeCorner)),
+			Size:   c.badgeSize,
+		}
+
+		if c.badgeColor != "" {
+			opts.Badge.Color = "#" + strings.TrimPrefix(c.badgeColor, "#")
+		}
+	}
+
+	if c.a11y || c.title != "" || c.desc != "" || c.hideName {
+		opts.Accessibility = &avatars.Accessibility{
+			Title:    c.title,
+			HideName: c.hideName,
+			Desc:     c.desc,
+		}
+	}
+
+	var write func(io.Writer, avatars.Options) error
+
+	switch strings.ToLower(c.format) {
+	case "svg":
+		write = avatars.Write
+	case "png":
+		write = raster.WritePNG
+	default:
+		return fmt.Errorf("invalid format %q (svg, png)", c.format)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	// Write the avatar out
+	if c.outputPath == "" {
+		return write(os.Stdout, opts)
+	}
+
+	outputFile, err := os.Create(c.outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to create output file, %w", err)
+	}
+
+	defer outputFile.Close()
+
+	if err := write(outputFile, opts); err != nil {
+		return fmt.Errorf("unable to write output file, %w", err)
+	}
+
+	return nil
+}
+
+// parseColors parses the comma-separated list of hex colors, with an optional leading #.
+// The colors are validated along with the other render options
+func parseColors(list string) avatars.Palette {
+	var palette avatars.Palette
+
+	for _, color := range strings.Split(list, ",") {
+		color = strings.TrimSpace(color)
+
+		if !strings.HasPrefix(color, "#") {
+			color = "#" + color
+		}
+
+		palette = append(palette, color)
+	}
+
+	return palette
+}
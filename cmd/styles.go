@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"github.com/sig-0/boring-avatars-go/avatars"
+)
+
+// newStylesCmd creates the styles command
+func newStylesCmd() *ffcli.Command {
+	return &ffcli.Command{
+		Name:       "styles",
+		ShortUsage: "styles",
+		LongHelp:   "Lists the available avatar styles",
+		FlagSet:    flag.NewFlagSet("styles", flag.ExitOnError),
+		Exec: func(_ context.Context, _ []string) error {
+			for _, style := range avatars.Styles() {
+				fmt.Println(style)
+			}
+
+			return nil
+		},
+	}
+}
@@ -1,36 +1,213 @@
 package server
 
 import (
-	"encoding/hex"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/httplog/v3"
 	"github.com/sig-0/boring-avatars-go/avatars"
+	"github.com/sig-0/boring-avatars-go/avatars/raster"
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	defaultVariant = avatars.Marble
 	defaultSize    = 80 // px
 
-	nameParam    = "name"
-	variantParam = "variant"
-	sizeParam    = "size"
-	squareParam  = "square"
-	colorsParam  = "colors"
+	nameParam       = "name"
+	variantParam    = "variant"
+	sizeParam       = "size"
+	squareParam     = "square"
+	shapeParam      = "shape"
+	radiusParam     = "radius"
+	colorsParam     = "colors"
+	formatParam     = "format"
+	normalizeParam  = "normalize"
+	paletteParam    = "palette"
+	idPrefixParam   = "id_prefix"
+	uniqueIDsParam  = "unique_ids"
+	a11yParam       = "a11y"
+	titleParam      = "title"
+	descParam       = "desc"
+	hideNameParam   = "hide_name"
+	initialsParam   = "initials"
+	animateParam    = "animate"
+	darkColorsParam = "dark_colors"
+
+	borderParam      = "border"
+	borderWidthParam = "border_width"
+	borderColorParam = "border_color"
+	badgeParam       = "badge"
+	badgeColorParam  = "badge_color"
+	badgeCornerParam = "badge_corner"
+	badgeSizeParam   = "badge_size"
+
+	unmaskedParam    = "unmasked"
+	transparentParam = "transparent"
 )
 
+// avatarRequest is a parsed, validated avatar request
+type avatarRequest struct {
+	opts       avatars.Options
+	format     format
+	negotiated bool // the format was negotiated through the Accept header
+	random     bool // no name was given, so the avatar is random
+}
+
 // avatarHandler serves
-// GET /?name&variant&size&colors&square
-func avatarHandler(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
+// GET /?name&variant&size&colors&palette&square&shape&radius&format&normalize&id_prefix&unique_ids
+// &dark_colors&a11y&title&desc&hide_name&initials&animate
+// &border&border_width&border_color&badge&badge_color&badge_corner&badge_size
+// &unmasked&transparent
+func (s *Server) avatarHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := s.parseAvatarRequest(ctx, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setRequestLabels(ctx, string(req.opts.Style), req.format)
+
+	if req.negotiated {
+		// The response depends on the Accept header
+		w.Header().Add("Vary", "Accept")
+	}
+
+	// Revalidate against the ETag, without generating anything.
+	// Random avatars differ on every request, so they don't get one
+	var etag string
+
+	if !req.random {
+		etag = avatarETag(req.opts, req.format)
+
+		if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+			setCacheHeaders(w, etag)
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+	}
+
+	// Serve the avatar from the render cache, if possible.
+	// The ETag doubles as the cache key, as it covers all the render params
+	cacheable := !req.random && s.cache != nil
+
+	if cacheable {
+		body, ok := s.cache.get(etag)
+
+		cacheResult := "miss"
+		if ok {
+			cacheResult = "hit"
+		}
+
+		httplog.SetAttrs(ctx, slog.String("cache", cacheResult))
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("avatar.cache", cacheResult))
+
+		if ok {
+			writeAvatar(w, req.format, etag, body)
+
+			return
+		}
+	}
+
+	start := time.Now()
+
+	sc, err := s.generateAvatar(ctx, req.opts)
+	if err != nil {
+		s.logger.Error("unable to generate avatar", "style", req.opts.Style, "err", err)
+
+		http.Error(w, "unable to generate avatar", http.StatusInternalServerError)
+
+		return
+	}
+
+	if !req.format.raster() && !cacheable {
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		setCacheHeaders(w, etag)
+
+		// Stream the SVG directly into the response
+		_ = s.encodeAvatar(ctx, w, sc, req.opts.Size, req.format)
+
+		s.metrics.observeRender(string(req.opts.Style), req.format, start)
 
+		return
+	}
+
+	// Encode the avatar before writing anything out,
+	// so encoding errors can still be reported
+	var b bytes.Buffer
+
+	if err := s.encodeAvatar(ctx, &b, sc, req.opts.Size, req.format); err != nil {
+		s.logger.Error("unable to encode avatar", "format", req.format, "err", err)
+
+		http.Error(w, "unable to encode avatar", http.StatusInternalServerError)
+
+		return
+	}
+
+	s.metrics.observeRender(string(req.opts.Style), req.format, start)
+
+	if cacheable {
+		s.cache.add(etag, b.Bytes())
+	}
+
+	writeAvatar(w, req.format, etag, b.Bytes())
+}
+
+// parseAvatarRequest parses and validates the avatar request params
+func (s *Server) parseAvatarRequest(ctx context.Context, r *http.Request) (req *avatarRequest, err error) {
+	ctx, span := s.startSpan(ctx, "parse request")
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	opts, random, err := s.parseAvatarOptions(ctx, r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the output format
+	format, negotiated, err := formatFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("avatar.style", string(opts.Style)),
+		attribute.Int("avatar.size", opts.Size),
+		attribute.String("avatar.format", string(format)),
+	)
+
+	return &avatarRequest{
+		opts:       opts,
+		format:     format,
+		negotiated: negotiated,
+		random:     random,
+	}, nil
+}
+
+// parseAvatarOptions parses and validates the avatar render params.
+// Random avatars (without a name) get a time-based name
+func (s *Server) parseAvatarOptions(ctx context.Context, q url.Values) (avatars.Options, bool, error) {
 	// Fetch the name
 	name := q.Get(nameParam)
-	if name == "" {
+
+	random := name == ""
+	if random {
 		// No name provided, generate a random avatar
 		name = fmt.Sprintf("%d", time.Now().UnixNano())
 	}
@@ -42,9 +219,7 @@ func avatarHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !avatars.ValidStyle(variant) {
-		http.Error(w, "invalid variant", http.StatusBadRequest)
-
-		return
+		return avatars.Options{}, false, fmt.Errorf("invalid variant (%s)", joinStyles(avatars.Styles()))
 	}
 
 	// Fetch the size
@@ -52,10 +227,8 @@ func avatarHandler(w http.ResponseWriter, r *http.Request) {
 
 	if sz := q.Get(sizeParam); sz != "" {
 		n, err := strconv.Atoi(sz)
-		if err != nil || n <= 0 || n > 512 { // TODO expose this in the config
-			http.Error(w, "invalid size (1-512)", http.StatusBadRequest)
-
-			return
+		if err != nil || n <= 0 || n > s.config.MaxSize {
+			return avatars.Options{}, false, fmt.Errorf("invalid size (1-%d)", s.config.MaxSize)
 		}
 
 		size = n
@@ -67,33 +240,270 @@ func avatarHandler(w http.ResponseWriter, r *http.Request) {
 		square = true
 	}
 
+	// Fetch the mask shape. A radius alone implies a rounded shape
+	shape := avatars.Shape(strings.ToLower(q.Get(shapeParam)))
+
+	var radius float64
+
+	if v := q.Get(radiusParam); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return avatars.Options{}, false, errors.New("radius must be a number in [0, 0.5]")
+		}
+
+		radius = r
+	}
+
 	// Fetch the color palette
-	var palette avatars.Palette
+	palette, err := s.parsePalette(ctx, q)
+	if err != nil {
+		return avatars.Options{}, false, err
+	}
+
+	// Fetch the dark color palette
+	darkPalette, err := parseColors(q.Get(darkColorsParam))
+	if err != nil {
+		return avatars.Options{}, false, errors.New("dark_colors must be 6-digit hex, comma-separated")
+	}
 
-	if cs := q.Get(colorsParam); cs != "" {
-		for _, c := range strings.Split(cs, ",") {
-			c = strings.TrimSpace(c)
+	// Fetch the name normalization steps
+	normalize := s.normalize
 
-			if !strings.HasPrefix(c, "#") {
-				c = "#" + c
+	if v, ok := q[normalizeParam]; ok {
+		n, err := avatars.ParseNormalization(strings.Join(v, ","))
+		if err != nil {
+			return avatars.Options{}, false, errors.New(
+				"normalize must be a comma-separated list of trim, nfc, nfkc, casefold, email or identity",
+			)
+		}
+
+		normalize = n
+	}
+
+	opts := avatars.Options{
+		Style:       variant,
+		Name:        name,
+		Palette:     palette,
+		DarkPalette: darkPalette,
+		Size:        size,
+		Square:      square,
+		Shape:       shape,
+		Radius:      radius,
+		Unmasked:    q.Get(unmaskedParam) == "true",
+		Transparent: q.Get(transparentParam) == "true",
+		Hasher:      s.hasher,
+		Normalize:   normalize,
+		IDPrefix:    q.Get(idPrefixParam),
+		UniqueIDs:   q.Get(uniqueIDsParam) == "true",
+		Initials:    q.Get(initialsParam) == "true",
+		Animate:     q.Get(animateParam) == "true",
+	}
+
+	// Fetch the accessibility params. Any of them enables the accessible output
+	var (
+		title    = q.Get(titleParam)
+		desc     = q.Get(descParam)
+		hideName = q.Get(hideNameParam) == "true"
+	)
+
+	if q.Get(a11yParam) == "true" || title != "" || desc != "" || hideName {
+		opts.Accessibility = &avatars.Accessibility{
+			Title:    title,
+			HideName: hideName || random, // random avatars get the anonymous title
+			Desc:     desc,
+		}
+	}
+
+	if err := parseDecorations(q, &opts); err != nil {
+		return avatars.Options{}, false, err
+	}
+
+	if err := opts.Validate(); err != nil {
+		return avatars.Options{}, false, err
+	}
+
+	return opts, random, nil
+}
+
+// parseDecorations parses the border and badge params, if any.
+// Any of the border (or badge) params enables the border (or badge)
+func parseDecorations(q url.Values, opts *avatars.Options) error {
+	var (
+		borderWidth = q.Get(borderWidthParam)
+		borderColor = q.Get(borderColorParam)
+	)
+
+	if q.Get(borderParam) == "true" || borderWidth != "" || borderColor != "" {
+		opts.Border = &avatars.Border{}
+
+		if borderWidth != "" {
+			w, err := strconv.ParseFloat(borderWidth, 64)
+			if err != nil {
+				return avatars.ErrInvalidBorder
 			}
 
-			_, err := hex.DecodeString(c[1:])
-			if len(c) != 7 || err != nil {
-				http.Error(w, "colors must be 6-digit hex, comma-separated", http.StatusBadRequest)
+			opts.Border.Width = w
+		}
 
-				return
+		if borderColor != "" {
+			opts.Border.Color = "#" + strings.TrimPrefix(borderColor, "#")
+		}
+	}
+
+	var (
+		status     = q.Get(badgeParam)
+		badgeColor = q.Get(badgeColorParam)
+	)
+
+	if status != "" || badgeColor != "" {
+		opts.Badge = &avatars.Badge{
+			Status: avatars.Status(strings.ToLower(status)),
+			Corner: avatars.Corner(strings.ToLower(q.Get(badgeCornerParam))),
+		}
+
+		if badgeColor != "" {
+			opts.Badge.Color = "#" + strings.TrimPrefix(badgeColor, "#")
+		}
+
+		if v := q.Get(badgeSizeParam); v != "" {
+			size, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return avatars.ErrInvalidBadge
 			}
 
-			palette = append(palette, c)
+			opts.Badge.Size = size
+		}
+	}
+
+	return nil
+}
+
+// parsePalette parses and validates the colors or palette param, if any
+func (s *Server) parsePalette(ctx context.Context, q url.Values) (palette avatars.Palette, err error) {
+	_, span := s.startSpan(ctx, "validate palette")
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	palette, err = parseColors(q.Get(colorsParam))
+	if err != nil {
+		return nil, errors.New("colors must be 6-digit hex, comma-separated")
+	}
+
+	// Fetch the named palette
+	if paletteName := q.Get(paletteParam); paletteName != "" {
+		if palette != nil {
+			return nil, errors.New("colors and palette are mutually exclusive")
+		}
+
+		named, ok := s.lookupPalette(paletteName)
+		if !ok {
+			return nil, errors.New("unknown palette, see /palettes")
 		}
+
+		span.SetAttributes(attribute.String("avatar.palette", paletteName))
+
+		palette = named
+	}
+
+	return palette, nil
+}
+
+// parseColors parses the comma-separated list of hex colors, with an optional leading #.
+// An empty list yields a nil palette
+func parseColors(list string) (avatars.Palette, error) {
+	if list == "" {
+		return nil, nil
 	}
 
-	// Generate the SVG
-	svg := avatars.Generate(variant, name, palette, size, square)
+	var palette avatars.Palette
+
+	for _, c := range strings.Split(list, ",") {
+		c = strings.TrimSpace(c)
+
+		if !strings.HasPrefix(c, "#") {
+			c = "#" + c
+		}
+
+		if !avatars.ValidColor(c) {
+			return nil, fmt.Errorf("%w: %q", avatars.ErrInvalidColor, c)
+		}
+
+		palette = append(palette, c)
+	}
+
+	return palette, nil
+}
+
+// generateAvatar builds the avatar scene
+func (s *Server) generateAvatar(ctx context.Context, opts avatars.Options) (sc *scene.Scene, err error) {
+	_, span := s.startSpan(
+		ctx,
+		"generate "+string(opts.Style),
+		attribute.String("avatar.style", string(opts.Style)),
+	)
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	return avatars.Build(opts)
+}
 
-	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+// encodeAvatar encodes the avatar scene in the given format
+func (s *Server) encodeAvatar(
+	ctx context.Context,
+	w io.Writer,
+	sc *scene.Scene,
+	size int,
+	f format,
+) (err error) {
+	_, span := s.startSpan(ctx, "encode "+string(f), attribute.String("avatar.format", string(f)))
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	if !f.raster() {
+		return scene.WriteSVG(w, sc)
+	}
+
+	img, err := raster.RenderScene(sc, size)
+	if err != nil {
+		return err
+	}
+
+	return encodeImage(w, img, f)
+}
+
+// writeAvatar writes out the rendered avatar, along with its headers
+func writeAvatar(w http.ResponseWriter, f format, etag string, body []byte) {
+	contentType := f.contentType()
+	if !f.raster() {
+		contentType += "; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	setCacheHeaders(w, etag)
+
+	_, _ = w.Write(body)
+}
+
+// setCacheHeaders sets the caching headers of the avatar response.
+// The ETag is omitted if empty
+func setCacheHeaders(w http.ResponseWriter, etag string) {
 	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
 
-	_, _ = io.WriteString(w, svg)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// joinStyles joins the styles into a comma separated list
+func joinStyles(styles []avatars.Style) string {
+	names := make([]string, len(styles))
+
+	for i, style := range styles {
+		names[i] = string(style)
+	}
+
+	return strings.Join(names, ", ")
 }
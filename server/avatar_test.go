@@ -0,0 +1,819 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/sig-0/boring-avatars-go/avatars"
+	"github.com/sig-0/boring-avatars-go/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer creates a server with the default configuration
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+
+	s, err := New(opts...)
+	require.NoError(t, err)
+
+	return s
+}
+
+// get executes a GET request against the server
+func get(t *testing.T, s *Server, target string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestAvatarHandler_Format(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("svg by default", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/?name=Grace")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/svg+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+	})
+
+	t.Run("browser accept header", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(
+			t, s, "/?name=Grace",
+			"Accept", "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8",
+		)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/svg+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("raster accept header", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/?name=Grace&size=64", "Accept", "image/png")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+
+		img, err := png.Decode(rec.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, image.Rect(0, 0, 64, 64), img.Bounds())
+	})
+
+	t.Run("format param", func(t *testing.T) {
+		t.Parallel()
+
+		testTable := []struct {
+			decode      func(b *bytes.Buffer) (image.Image, error)
+			format      string
+			contentType string
+		}{
+			{
+				format:      "png",
+				contentType: "image/png",
+				decode:      func(b *bytes.Buffer) (image.Image, error) { return png.Decode(b) },
+			},
+			{
+				format:      "webp",
+				contentType: "image/webp",
+				decode:      func(b *bytes.Buffer) (image.Image, error) { return nativewebp.Decode(b) },
+			},
+			{
+				format:      "jpg",
+				contentType: "image/jpeg",
+				decode:      func(b *bytes.Buffer) (image.Image, error) { return jpeg.Decode(b) },
+			},
+		}
+
+		for _, testCase := range testTable {
+			t.Run(testCase.format, func(t *testing.T) {
+				t.Parallel()
+
+				// The format param takes precedence over the Accept header
+				rec := get(
+					t, s, "/?name=Grace&variant=beam&size=32&format="+testCase.format,
+					"Accept", "image/svg+xml",
+				)
+
+				require.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, testCase.contentType, rec.Header().Get("Content-Type"))
+				assert.NotContains(t, rec.Header().Values("Vary"), "Accept")
+
+				img, err := testCase.decode(rec.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, image.Rect(0, 0, 32, 32), img.Bounds())
+			})
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/?name=Grace&format=gif")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/?format=png&size=513").Code)
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/?size=0").Code)
+	})
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	t.Parallel()
+
+	testTable := []struct {
+		accept string
+		want   format
+	}{
+		{"", formatSVG},
+		{"*/*", formatSVG},
+		{"image/*", formatSVG},
+		{"text/html", formatSVG},
+		{"image/png", formatPNG},
+		{"image/webp,image/png;q=0.9", formatWebP},
+		{"image/svg+xml;q=0.5,image/png", formatPNG},
+		{"image/jpeg,image/*;q=0.1", formatJPEG},
+		{"image/*,image/svg+xml;q=0", formatPNG},
+	}
+
+	for _, testCase := range testTable {
+		assert.Equal(t, testCase.want, negotiateFormat(testCase.accept), testCase.accept)
+	}
+}
+
+func TestStylesHandler(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/styles")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var resp stylesResponse
+
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Subset(t, resp.Styles, []avatars.Style{avatars.Beam, avatars.Marble, avatars.Sunset})
+	})
+
+	t.Run("invalid variant", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/?name=Grace&variant=cubist")
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "beam, bauhaus, marble")
+	})
+}
+
+func TestAvatarHandler_Hasher(t *testing.T) {
+	t.Parallel()
+
+	// "Aa" and "BB" collide with the default (Java-style) hasher
+	defaultServer := newTestServer(t)
+
+	assert.Equal(
+		t,
+		get(t, defaultServer, "/?name=Aa").Body.String(),
+		get(t, defaultServer, "/?name=BB").Body.String(),
+	)
+
+	cfg := config.DefaultConfig()
+	cfg.Hasher = config.HasherFNV1a
+
+	s := newTestServer(t, WithConfig(cfg))
+
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=Aa").Body.String(),
+		get(t, s, "/?name=BB").Body.String(),
+	)
+}
+
+func TestAvatarHandler_Normalize(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("param", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotEqual(
+			t,
+			get(t, s, "/?name=Alice%40Example.com").Body.String(),
+			get(t, s, "/?name=alice%40example.com%20").Body.String(),
+		)
+
+		assert.Equal(
+			t,
+			get(t, s, "/?name=Alice%40Example.com&normalize=identity").Body.String(),
+			get(t, s, "/?name=alice%40example.com%20&normalize=trim,casefold").Body.String(),
+		)
+	})
+
+	t.Run("config default", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.DefaultConfig()
+		cfg.Normalize = "identity"
+
+		ns := newTestServer(t, WithConfig(cfg))
+
+		assert.Equal(
+			t,
+			get(t, ns, "/?name=Alice%2Bwork%40Example.com").Body.String(),
+			get(t, ns, "/?name=alice%40example.com").Body.String(),
+		)
+
+		// Requests can opt out
+		assert.NotEqual(
+			t,
+			get(t, ns, "/?name=Alice%40Example.com&normalize=none").Body.String(),
+			get(t, ns, "/?name=alice%40example.com").Body.String(),
+		)
+	})
+
+	t.Run("invalid steps", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/?normalize=lowercase").Code)
+	})
+}
+
+func TestAvatarHandler_Palette(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DefaultConfig()
+	cfg.Palettes = map[string][]string{
+		"Brand": {"#112233", "#445566"},
+		"neon":  {"#000000"}, // overrides the catalog palette
+		"zest":  {"#FFD000"},
+		"aqua":  {"#00FFFF"},
+	}
+
+	s := newTestServer(t, WithConfig(cfg))
+
+	t.Run("catalog palette", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(
+			t,
+			get(t, s, "/?name=Grace&colors=69D2E7,A7DBD8,E0E4CC,F38630,FA6900").Body.String(),
+			get(t, s, "/?name=Grace&palette=nice-1").Body.String(),
+		)
+	})
+
+	t.Run("config palette", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(
+			t,
+			get(t, s, "/?name=Grace&colors=112233,445566").Body.String(),
+			get(t, s, "/?name=Grace&palette=brand").Body.String(),
+		)
+
+		assert.Equal(
+			t,
+			get(t, s, "/?name=Grace&colors=000000").Body.String(),
+			get(t, s, "/?name=Grace&palette=neon").Body.String(),
+		)
+	})
+
+	t.Run("invalid palette", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/?palette=missing").Code)
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/?palette=brand&colors=000000").Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/palettes")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		body := rec.Body.String()
+
+		var resp palettesResponse
+
+		require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+		listed := make(map[string]avatars.Palette)
+
+		for _, p := range resp.Palettes {
+			listed[p.Name] = p.Colors
+		}
+
+		assert.Equal(t, avatars.Palette{"#112233", "#445566"}, listed["brand"])
+		assert.Equal(t, avatars.Palette{"#000000"}, listed["neon"])
+		assert.Contains(t, listed, "boring")
+
+		// The other config palettes follow the catalog ones, sorted
+		names := make([]string, 0, 3)
+
+		for _, p := range resp.Palettes[len(resp.Palettes)-3:] {
+			names = append(names, p.Name)
+		}
+
+		assert.Equal(t, []string{"aqua", "brand", "zest"}, names)
+
+		// So the response is stable
+		for range 5 {
+			assert.Equal(t, body, get(t, s, "/palettes").Body.String())
+		}
+	})
+}
+
+func TestAvatarPathHandler(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("same as the query interface", func(t *testing.T) {
+		t.Parallel()
+
+		testTable := []struct {
+			path, query string
+		}{
+			{"/beam/120/Grace.svg", "/?variant=beam&size=120&name=Grace&format=svg"},
+			{"/ring/Grace%20Hopper.svg", "/?variant=ring&name=Grace%20Hopper&format=svg"},
+			{"/pixel/64/grace.hopper%40example.com.png", "/?variant=pixel&size=64&name=grace.hopper%40example.com&format=png"},
+			{
+				"/sunset/Grace.webp?square=true&colors=000000,FFFFFF",
+				"/?variant=sunset&name=Grace&format=webp&square=true&colors=000000,FFFFFF",
+			},
+			{"/marble/100%25", "/?variant=marble&name=100%25"},
+		}
+
+		for _, testCase := range testTable {
+			pathRec := get(t, s, testCase.path)
+			queryRec := get(t, s, testCase.query)
+
+			require.Equal(t, http.StatusOK, pathRec.Code, testCase.path)
+			assert.Equal(t, queryRec.Header().Get("Content-Type"), pathRec.Header().Get("Content-Type"), testCase.path)
+			assert.Equal(t, queryRec.Body.Bytes(), pathRec.Body.Bytes(), testCase.path)
+		}
+	})
+
+	t.Run("path params take precedence", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(
+			t,
+			get(t, s, "/beam/Grace.svg").Body.String(),
+			get(t, s, "/beam/Grace.svg?variant=ring&name=Ada").Body.String(),
+		)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/cubist/Grace.svg").Code)
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/beam/0/Grace.svg").Code)
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/beam/huge/Grace.png").Code)
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/beam/.png").Code)
+	})
+
+	t.Run("static routes still match", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusOK, get(t, s, "/health").Code)
+		assert.Equal(t, "application/json", get(t, s, "/styles").Header().Get("Content-Type"))
+	})
+}
+
+func TestAvatarHandler_ETag(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("stable", func(t *testing.T) {
+		t.Parallel()
+
+		etag := get(t, s, "/?name=Grace&variant=beam").Header().Get("ETag")
+
+		require.NotEmpty(t, etag)
+		assert.Equal(t, etag, get(t, s, "/?variant=beam&name=Grace").Header().Get("ETag"))
+		assert.Equal(t, etag, get(t, s, "/beam/Grace").Header().Get("ETag"))
+
+		// Any param change is a different avatar
+		for _, target := range []string{
+			"/?name=Ada&variant=beam",
+			"/?name=Grace&variant=ring",
+			"/?name=Grace&variant=beam&size=81",
+			"/?name=Grace&variant=beam&square=true",
+			"/?name=Grace&variant=beam&palette=nice-1",
+			"/?name=Grace&variant=beam&format=png",
+			"/?name=Grace&variant=beam&id_prefix=a_",
+			"/?name=Grace&variant=beam&unique_ids=true",
+		} {
+			assert.NotEqual(t, etag, get(t, s, target).Header().Get("ETag"), target)
+		}
+	})
+
+	t.Run("normalized names", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(
+			t,
+			get(t, s, "/?name=Grace%40Example.com&normalize=identity").Header().Get("ETag"),
+			get(t, s, "/?name=grace%40example.com").Header().Get("ETag"),
+		)
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		t.Parallel()
+
+		etag := get(t, s, "/?name=Grace&format=png").Header().Get("ETag")
+
+		for _, match := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			rec := get(t, s, "/?name=Grace&format=png", "If-None-Match", match)
+
+			require.Equal(t, http.StatusNotModified, rec.Code, match)
+			assert.Empty(t, rec.Body.Bytes())
+			assert.Equal(t, etag, rec.Header().Get("ETag"))
+			assert.NotEmpty(t, rec.Header().Get("Cache-Control"))
+		}
+
+		rec := get(t, s, "/?name=Grace&format=png", "If-None-Match", `"other"`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("negotiated format", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/?name=Grace", "Accept", "image/png", "If-None-Match", "*")
+
+		require.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+
+		assert.NotEqual(
+			t,
+			get(t, s, "/?name=Grace", "Accept", "image/png").Header().Get("ETag"),
+			get(t, s, "/?name=Grace", "Accept", "image/svg+xml").Header().Get("ETag"),
+		)
+	})
+
+	t.Run("random avatars", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, get(t, s, "/").Header().Get("ETag"))
+	})
+}
+
+func TestAvatarHandler_Cache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cached renders", func(t *testing.T) {
+		t.Parallel()
+
+		s := newTestServer(t)
+
+		for _, target := range []string{"/?name=Grace", "/?name=Grace&format=png"} {
+			first := get(t, s, target)
+			second := get(t, s, target)
+
+			require.Equal(t, http.StatusOK, second.Code)
+			assert.Equal(t, first.Body.Bytes(), second.Body.Bytes())
+			assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+			assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+		}
+
+		stats := s.CacheStats()
+
+		assert.Equal(t, uint64(2), stats.Hits)
+		assert.Equal(t, uint64(2), stats.Misses)
+		assert.Equal(t, 2, stats.Entries)
+	})
+
+	t.Run("random avatars", func(t *testing.T) {
+		t.Parallel()
+
+		s := newTestServer(t)
+
+		get(t, s, "/")
+
+		assert.Equal(t, CacheStats{}, s.CacheStats())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.DefaultConfig()
+		cfg.Cache.Disabled = true
+
+		s := newTestServer(t, WithConfig(cfg))
+
+		get(t, s, "/?name=Grace")
+		rec := get(t, s, "/?name=Grace")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEmpty(t, rec.Body.Bytes())
+		assert.Equal(t, CacheStats{}, s.CacheStats())
+	})
+}
+
+func TestAvatarHandler_IDPrefix(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("explicit prefix", func(t *testing.T) {
+		t.Parallel()
+
+		rec := get(t, s, "/?name=Grace&variant=marble&id_prefix=user-42_")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<mask id="user-42_mask_marble_`)
+		assert.Contains(t, rec.Body.String(), `filter="url(#user-42_filter_mask_marble_`)
+	})
+
+	t.Run("unique IDs", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			a = get(t, s, "/?name=Grace&unique_ids=true").Body.String()
+			b = get(t, s, "/?name=Grace&unique_ids=true&palette=nice-1").Body.String()
+		)
+
+		assert.Regexp(t, `<mask id="a[0-9a-f]{12}_mask_marble_`, a)
+		assert.NotEqual(t, a[:strings.Index(a, "_mask")], b[:strings.Index(b, "_mask")])
+	})
+
+	t.Run("invalid prefix", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusBadRequest, get(t, s, "/?name=Grace&id_prefix=1a").Code)
+	})
+}
+
+func TestAvatarHandler_Accessibility(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	for _, tc := range []struct {
+		target   string
+		contains []string
+	}{
+		{"/?name=Grace", []string{`role="img"`}},
+		{"/?name=Grace&a11y=true", []string{"aria-labelledby", ">Grace</title>"}},
+		{"/?name=Grace&title=Our%20Grace", []string{">Our Grace</title>"}},
+		{"/?name=Grace&hide_name=true", []string{">Avatar</title>"}},
+		{"/?name=Grace&desc=A%20marble", []string{">Grace</title>", "aria-describedby", ">A marble</desc>"}},
+		{"/?a11y=true", []string{">Avatar</title>"}},
+		{"/?a11y=true&title=Guest", []string{">Guest</title>"}},
+	} {
+		rec := get(t, s, tc.target)
+
+		require.Equal(t, http.StatusOK, rec.Code, tc.target)
+
+		for _, c := range tc.contains {
+			assert.Contains(t, rec.Body.String(), c, tc.target)
+		}
+	}
+
+	assert.NotContains(t, get(t, s, "/?name=Grace").Body.String(), "<title")
+
+	// Names sharing a seed still differ in their default title
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=Grace&a11y=true&normalize=casefold").Header().Get("ETag"),
+		get(t, s, "/?name=grace&a11y=true&normalize=casefold").Header().Get("ETag"),
+	)
+}
+
+func TestAvatarHandler_Initials(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	rec := get(t, s, "/?name=Grace%20Hopper&initials=true")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), ">GH</text>")
+
+	assert.NotContains(t, get(t, s, "/?name=Grace%20Hopper").Body.String(), "<text")
+
+	// PNGs render the initials as well
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=Grace%20Hopper&format=png").Body.Bytes(),
+		get(t, s, "/?name=Grace%20Hopper&format=png&initials=true").Body.Bytes(),
+	)
+
+	// Names sharing a seed still differ in their initials (a fullwidth G here)
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=%EF%BD%87race&initials=true&normalize=nfkc").Header().Get("ETag"),
+		get(t, s, "/?name=grace&initials=true&normalize=nfkc").Header().Get("ETag"),
+	)
+}
+
+func TestAvatarHandler_Animate(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	rec := get(t, s, "/?name=Grace&variant=ring&animate=true")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "@keyframes anim_spin_ring_")
+	assert.Contains(t, rec.Body.String(), "prefers-reduced-motion")
+
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=Grace&variant=ring").Header().Get("ETag"),
+		rec.Header().Get("ETag"),
+	)
+
+	// Raster images are still, so they share the static avatar ETag
+	assert.Equal(
+		t,
+		get(t, s, "/?name=Grace&variant=ring&format=png").Header().Get("ETag"),
+		get(t, s, "/?name=Grace&variant=ring&format=png&animate=true").Header().Get("ETag"),
+	)
+}
+
+func TestAvatarHandler_DarkColors(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	rec := get(t, s, "/?name=Grace&variant=bauhaus&colors=264653&dark_colors=%230B132B")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `fill="#264653" class="dark-fill-0B132B"`)
+	assert.Contains(t, rec.Body.String(), "@media (prefers-color-scheme:dark)")
+
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=Grace&variant=bauhaus&colors=264653").Header().Get("ETag"),
+		rec.Header().Get("ETag"),
+	)
+
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/?name=Grace&dark_colors=264653,teal").Code)
+}
+
+func TestAvatarHandler_Decorations(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	for _, tc := range []struct {
+		target   string
+		contains string
+	}{
+		{"/?name=Grace&border=true", `stroke-width="4" stroke="#FFFFFF" fill="none"/>`},
+		{"/?name=Grace&border_width=0.1&border_color=222222", `stroke-width="8" stroke="#222222" fill="none"/>`},
+		{"/?name=Grace&badge=online", `<circle cx="68.28" cy="68.28" r="10" fill="#2ECC71"/>`},
+		{"/?name=Grace&square=true&badge=busy&badge_corner=top-left&badge_size=0.2", `r="8" fill="#E74C3C"/>`},
+		{"/?name=Grace&badge_color=%23123456", `fill="#123456"/>`},
+	} {
+		rec := get(t, s, tc.target)
+
+		require.Equal(t, http.StatusOK, rec.Code, tc.target)
+		assert.Contains(t, rec.Body.String(), tc.contains, tc.target)
+	}
+
+	for _, target := range []string{
+		"/?name=Grace&border_width=thick",
+		"/?name=Grace&border_width=0.9",
+		"/?name=Grace&border_width=NaN",
+		"/?name=Grace&border_color=white",
+		"/?name=Grace&badge=asleep",
+		"/?name=Grace&badge=online&badge_corner=middle",
+		"/?name=Grace&badge=online&badge_size=big",
+		"/?name=Grace&badge=typo&badge_color=FF0000",
+		"/?name=Grace&badge=online&badge_size=NaN",
+	} {
+		assert.Equal(t, http.StatusBadRequest, get(t, s, target).Code, target)
+	}
+
+	assert.NotEqual(
+		t,
+		get(t, s, "/?name=Grace&badge=online").Header().Get("ETag"),
+		get(t, s, "/?name=Grace&badge=away").Header().Get("ETag"),
+	)
+}
+
+func TestAvatarHandler_Shape(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	for _, tc := range []struct {
+		target   string
+		contains string
+	}{
+		{"/?name=Grace&shape=square", `<rect width="80" height="80" fill="#FFFFFF"/>`},
+		{"/?name=Grace&shape=rounded&radius=0.25", `<rect width="80" height="80" rx="20" fill="#FFFFFF"/>`},
+		{"/?name=Grace&radius=0.1", `<rect width="80" height="80" rx="8" fill="#FFFFFF"/>`},
+		{"/?name=Grace&shape=Hexagon", `<path d="M40 0L74.64 20L74.64 60L40 80L5.36 60L5.36 20Z" fill="#FFFFFF"/>`},
+		{"/?name=Grace&shape=squircle", `<path d="M0 40C0 4.8 4.8 0 40 0S80 4.8`},
+	} {
+		rec := get(t, s, tc.target)
+
+		require.Equal(t, http.StatusOK, rec.Code, tc.target)
+		assert.Contains(t, rec.Body.String(), tc.contains, tc.target)
+	}
+
+	for _, target := range []string{
+		"/?name=Grace&shape=star",
+		"/?name=Grace&radius=round",
+		"/?name=Grace&radius=0.6",
+		"/?name=Grace&radius=NaN",
+	} {
+		assert.Equal(t, http.StatusBadRequest, get(t, s, target).Code, target)
+	}
+
+	// The default round shape keeps its ETag, while the other shapes get their own
+	var (
+		round   = get(t, s, "/?name=Grace").Header().Get("ETag")
+		rounded = get(t, s, "/?name=Grace&shape=rounded&radius=0.2").Header().Get("ETag")
+		radius  = get(t, s, "/?name=Grace&shape=rounded&radius=0.3").Header().Get("ETag")
+	)
+
+	assert.NotEqual(t, round, rounded)
+	assert.NotEqual(t, rounded, radius)
+}
+
+func TestAvatarHandler_Compositing(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	var (
+		full        = get(t, s, "/?name=Grace&variant=beam")
+		unmasked    = get(t, s, "/?name=Grace&variant=beam&unmasked=true")
+		transparent = get(t, s, "/?name=Grace&variant=beam&transparent=true")
+	)
+
+	require.Equal(t, http.StatusOK, unmasked.Code)
+	require.Equal(t, http.StatusOK, transparent.Code)
+
+	assert.Contains(t, full.Body.String(), "<mask")
+	assert.NotContains(t, unmasked.Body.String(), "<mask")
+	assert.Contains(t, transparent.Body.String(), "<mask")
+	assert.Less(t, transparent.Body.Len(), full.Body.Len())
+
+	assert.NotEqual(t, full.Header().Get("ETag"), unmasked.Header().Get("ETag"))
+	assert.NotEqual(t, full.Header().Get("ETag"), transparent.Header().Get("ETag"))
+	assert.NotEqual(t, unmasked.Header().Get("ETag"), transparent.Header().Get("ETag"))
+
+	// Raster images keep the transparency: unmasked avatars fill their corners,
+	// unless the background is left out as well, leaving just the Ring rings
+	alpha := func(target string, x, y int) uint32 {
+		rec := get(t, s, target)
+		require.Equal(t, http.StatusOK, rec.Code, target)
+
+		img, err := png.Decode(rec.Body)
+		require.NoError(t, err)
+
+		_, _, _, a := img.At(x, y).RGBA()
+
+		return a
+	}
+
+	const ring = "/?name=Grace&variant=ring&size=90&format=png&unmasked=true"
+
+	assert.Equal(t, uint32(0xffff), alpha(ring, 1, 1))
+	assert.Zero(t, alpha(ring+"&transparent=true", 1, 1))
+	assert.Equal(t, uint32(0xffff), alpha(ring+"&transparent=true", 45, 45))
+}
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxBatchBodySize is the maximum batch request body size, in bytes
+	maxBatchBodySize = 1 << 20 // 1 MiB
+
+	// maxBatchPixels is the maximum total area of the raster images in a batch,
+	// which bounds the rendering work of a single request
+	maxBatchPixels = 16 * 512 * 512 // 16 raster images of 512px
+
+	multipartMediaType = "multipart/mixed"
+)
+
+// batchItem is a single avatar spec of the batch request
+type batchItem struct {
+	Name    string   `json:"name"`
+	Variant string   `json:"variant"`
+	Size    int      `json:"size"`
+	Colors  []string `json:"colors"`
+	Palette string   `json:"palette"`
+	Square  bool     `json:"square"`
+	Format  string   `json:"format"`
+
+	Shape  string  `json:"shape"`
+	Radius float64 `json:"radius"`
+
+	Unmasked    bool `json:"unmasked"`
+	Transparent bool `json:"transparent"`
+
+	IDPrefix  string `json:"id_prefix"`
+	UniqueIDs bool   `json:"unique_ids"`
+
+	A11y     bool   `json:"a11y"`
+	Title    string `json:"title"`
+	Desc     string `json:"desc"`
+	HideName bool   `json:"hide_name"`
+
+	Initials bool `json:"initials"`
+	Animate  bool `json:"animate"`
+
+	DarkColors []string `json:"dark_colors"`
+
+	Border      bool    `json:"border"`
+	BorderWidth float64 `json:"border_width"`
+	BorderColor string  `json:"border_color"`
+	Badge       string  `json:"badge"`
+	BadgeColor  string  `json:"badge_color"`
+	BadgeCorner string  `json:"badge_corner"`
+	BadgeSize   float64 `json:"badge_size"`
+}
+
+// batchResponse is the JSON batch response. SVGs are inlined,
+// while raster images are returned as base64 data URIs
+type batchResponse struct {
+	Avatars map[string]string `json:"avatars"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// batchResult is the outcome of a single batch item
+type batchResult struct {
+	name   string
+	format format
+	body   []byte
+	err    error
+}
+
+// batchHandler serves
+// POST /batch
+// taking a JSON array of avatar specs, and returning either a JSON object
+// of the avatars keyed by name, or a multipart/mixed response
+// if the client prefers it. Invalid items are reported individually,
+// while duplicate names and oversized raster images fail the whole batch
+func (s *Server) batchHandler(w http.ResponseWriter, r *http.Request) {
+	var items []batchItem
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&items); err != nil {
+		http.Error(w, "invalid batch, expected a JSON array of avatars", http.StatusBadRequest)
+
+		return
+	}
+
+	if len(items) == 0 || len(items) > s.config.MaxBatchSize {
+		http.Error(w, fmt.Sprintf("invalid batch size (1-%d)", s.config.MaxBatchSize), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := s.validateBatch(items); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	results, err := s.renderBatch(r.Context(), items)
+	if err != nil {
+		http.Error(w, "batch cancelled", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	accept := r.Header.Get("Accept")
+	if acceptQuality(accept, multipartMediaType) > acceptQuality(accept, "application/json") {
+		writeBatchMultipart(w, results)
+
+		return
+	}
+
+	resp := batchResponse{
+		Avatars: make(map[string]string, len(results)),
+	}
+
+	for _, res := range results {
+		if res.err != nil {
+			if resp.Errors == nil {
+				resp.Errors = make(map[string]string)
+			}
+
+			resp.Errors[res.name] = res.err.Error()
+
+			continue
+		}
+
+		if !res.format.raster() {
+			resp.Avatars[res.name] = string(res.body)
+
+			continue
+		}
+
+		resp.Avatars[res.name] = "data:" + res.format.contentType() + ";base64," +
+			base64.StdEncoding.EncodeToString(res.body)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// validateBatch checks the batch as a whole: the names key the results, so they must be unique,
+// and the raster images must fit the pixel budget. SVGs aren't rasterized, so their size is free
+func (s *Server) validateBatch(items []batchItem) error {
+	var (
+		seen   = make(map[string]struct{}, len(items))
+		pixels = 0
+	)
+
+	for _, item := range items {
+		if item.Name != "" {
+			if _, dup := seen[item.Name]; dup {
+				return fmt.Errorf("duplicate name %q", item.Name)
+			}
+
+			seen[item.Name] = struct{}{}
+		}
+
+		if f, ok := parseFormat(item.Format); !ok || !f.raster() {
+			continue
+		}
+
+		// Invalid sizes fail their item, so they don't count
+		size := item.Size
+		if size == 0 {
+			size = defaultSize
+		}
+
+		if size > 0 && size <= s.config.MaxSize {
+			pixels += size * size
+		}
+	}
+
+	if pixels > maxBatchPixels {
+		return fmt.Errorf("batch too large (up to %d raster image pixels)", maxBatchPixels)
+	}
+
+	return nil
+}
+
+// renderBatch renders the batch items concurrently, in the order they were given.
+// It stops rendering once the context is cancelled, returning its error
+func (s *Server) renderBatch(ctx context.Context, items []batchItem) ([]batchResult, error) {
+	results := make([]batchResult, len(items))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.GOMAXPROCS(0))
+
+	for i, item := range items {
+		results[i].name = item.Name
+
+		if item.Name == "" {
+			results[i].err = errors.New("missing name")
+
+			continue
+		}
+
+		// Items only fail the group through the cancelled context
+		g.Go(func() error {
+			if err := gCtx.Err(); err != nil {
+				return err
+			}
+
+			results[i].format, results[i].body, results[i].err = s.renderBatchItem(gCtx, item)
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// renderBatchItem renders a single batch item, through the render cache
+func (s *Server) renderBatchItem(ctx context.Context, item batchItem) (format, []byte, error) {
+	// Map the item onto the query params, so it's parsed like a regular request
+	q := url.Values{}
+
+	q.Set(nameParam, item.Name)
+	q.Set(variantParam, item.Variant)
+	q.Set(squareParam, strconv.FormatBool(item.Square))
+	q.Set(shapeParam, item.Shape)
+	q.Set(unmaskedParam, strconv.FormatBool(item.Unmasked))
+	q.Set(transparentParam, strconv.FormatBool(item.Transparent))
+	q.Set(paletteParam, item.Palette)
+	q.Set(colorsParam, strings.Join(item.Colors, ","))
+	q.Set(idPrefixParam, item.IDPrefix)
+	q.Set(uniqueIDsParam, strconv.FormatBool(item.UniqueIDs))
+	q.Set(a11yParam, strconv.FormatBool(item.A11y))
+	q.Set(titleParam, item.Title)
+	q.Set(descParam, item.Desc)
+	q.Set(hideNameParam, strconv.FormatBool(item.HideName))
+	q.Set(initialsParam, strconv.FormatBool(item.Initials))
+	q.Set(animateParam, strconv.FormatBool(item.Animate))
+	q.Set(darkColorsParam, strings.Join(item.DarkColors, ","))
+	q.Set(borderParam, strconv.FormatBool(item.Border))
+	q.Set(borderColorParam, item.BorderColor)
+	q.Set(badgeParam, item.Badge)
+	q.Set(badgeColorParam, item.BadgeColor)
+	q.Set(badgeCornerParam, item.BadgeCorner)
+
+	if item.Size != 0 {
+		q.Set(sizeParam, strconv.Itoa(item.Size))
+	}
+
+	if item.Radius != 0 {
+		q.Set(radiusParam, strconv.FormatFloat(item.Radius, 'g', -1, 64))
+	}
+
+	if item.BorderWidth != 0 {
+		q.Set(borderWidthParam, strconv.FormatFloat(item.BorderWidth, 'g', -1, 64))
+	}
+
+	if item.BadgeSize != 0 {
+		q.Set(badgeSizeParam, strconv.FormatFloat(item.BadgeSize, 'g', -1, 64))
+	}
+
+	opts, _, err := s.parseAvatarOptions(ctx, q)
+	if err != nil {
+		return "", nil, err
+	}
+
+	f := formatSVG
+
+	if item.Format != "" {
+		parsed, ok := parseFormat(item.Format)
+		if !ok {
+			return "", nil, fmt.Errorf("invalid format %q (svg, png, webp, jpeg)", item.Format)
+		}
+
+		f = parsed
+	}
+
+	key := avatarETag(opts, f)
+
+	if s.cache != nil {
+		if body, ok := s.cache.get(key); ok {
+			return f, body, nil
+		}
+	}
+
+	sc, err := s.generateAvatar(ctx, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b bytes.Buffer
+
+	if err := s.encodeAvatar(ctx, &b, sc, opts.Size, f); err != nil {
+		return "", nil, err
+	}
+
+	if s.cache != nil {
+		s.cache.add(key, b.Bytes())
+	}
+
+	return f, b.Bytes(), nil
+}
+
+// writeBatchMultipart writes out the batch results as a multipart/mixed response,
+// with a part per item. Failed items get a text/plain part with the error,
+// and an X-Status header of 400
+func writeBatchMultipart(w http.ResponseWriter, results []batchResult) {
+	mw := multipart.NewWriter(w)
+
+	w.Header().Set("Content-Type", multipartMediaType+"; boundary="+mw.Boundary())
+
+	for _, res := range results {
+		header := textproto.MIMEHeader{}
+
+		if res.err != nil {
+			header.Set("Content-Type", "text/plain; charset=utf-8")
+			header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"name": res.name}))
+			header.Set("X-Status", strconv.Itoa(http.StatusBadRequest))
+
+			part, _ := mw.CreatePart(header)
+			_, _ = part.Write([]byte(res.err.Error()))
+
+			continue
+		}
+
+		header.Set("Content-Type", res.format.contentType())
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"name":     res.name,
+			"filename": res.name + "." + string(res.format),
+		}))
+		header.Set("X-Status", strconv.Itoa(http.StatusOK))
+
+		part, _ := mw.CreatePart(header)
+		_, _ = part.Write(res.body)
+	}
+
+	_ = mw.Close()
+}
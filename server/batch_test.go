@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postBatch executes a batch request against the server
+func postBatch(t *testing.T, s *Server, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestBatchHandler(t *testing.T) {
+	t.Parallel()
+
+	const batch = `[
+		{"name": "Grace", "variant": "beam", "size": 40},
+		{"name": "Ada", "format": "png", "colors": ["264653", "2a9d8f"]},
+		{"name": "Alan", "size": 100000},
+		{"name": ""}
+	]`
+
+	s := newTestServer(t)
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		rec := postBatch(t, s, batch)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var resp batchResponse
+
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+		// The avatars match their single-avatar counterparts
+		assert.Equal(t, get(t, s, "/?name=Grace&variant=beam&size=40").Body.String(), resp.Avatars["Grace"])
+		assert.True(t, strings.HasPrefix(resp.Avatars["Ada"], "data:image/png;base64,"))
+
+		assert.Equal(t, map[string]string{
+			"Alan": "invalid size (1-512)",
+			"":     "missing name",
+		}, resp.Errors)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		t.Parallel()
+
+		rec := postBatch(t, s, batch, "Accept", "multipart/mixed")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		require.NoError(t, err)
+		require.Equal(t, "multipart/mixed", mediaType)
+
+		var (
+			mr       = multipart.NewReader(rec.Body, params["boundary"])
+			types    []string
+			statuses []string
+		)
+
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+
+			require.NoError(t, err)
+
+			types = append(types, part.Header.Get("Content-Type"))
+			statuses = append(statuses, part.Header.Get("X-Status"))
+		}
+
+		assert.Equal(t, []string{
+			"image/svg+xml",
+			"image/png",
+			"text/plain; charset=utf-8",
+			"text/plain; charset=utf-8",
+		}, types)
+		assert.Equal(t, []string{"200", "200", "400", "400"}, statuses)
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		t.Parallel()
+
+		rec := postBatch(t, s, `[{"name": "Grace"}, {"name": "Grace", "variant": "ring"}]`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `duplicate name "Grace"`)
+	})
+
+	t.Run("pixel budget", func(t *testing.T) {
+		t.Parallel()
+
+		items := make([]string, 0, 17)
+
+		for i := range 17 {
+			items = append(items, fmt.Sprintf(`{"name": "%d", "size": 512, "format": "png"}`, i))
+		}
+
+		// The raster images overflow the budget
+		rec := postBatch(t, s, "["+strings.Join(items, ",")+"]")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "batch too large")
+
+		// While SVGs, and the raster images that fit it, are rendered
+		assert.Equal(t, http.StatusOK, postBatch(t, s, "["+strings.Join(items[:2], ",")+"]").Code)
+		assert.Equal(
+			t,
+			http.StatusOK,
+			postBatch(t, s, strings.ReplaceAll("["+strings.Join(items, ",")+"]", `"png"`, `"svg"`)).Code,
+		)
+	})
+
+	t.Run("cancelled request", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/batch", strings.NewReader(batch))
+		rec := httptest.NewRecorder()
+
+		s.mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("id prefixes", func(t *testing.T) {
+		t.Parallel()
+
+		rec := postBatch(t, s, `[{"name": "Grace", "id_prefix": "g_"}, {"name": "Ada", "id_prefix": "1"}]`)
+
+		var resp batchResponse
+
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+		assert.Contains(t, resp.Avatars["Grace"], `<mask id="g_mask_marble_`)
+		assert.Contains(t, resp.Errors, "Ada")
+	})
+
+	t.Run("invalid batch", func(t *testing.T) {
+		t.Parallel()
+
+		for _, body := range []string{`{"name": "Grace"}`, `[]`, `not json`} {
+			assert.Equal(t, http.StatusBadRequest, postBatch(t, s, body).Code, body)
+		}
+	})
+
+	t.Run("max batch size", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.DefaultConfig()
+		cfg.MaxBatchSize = 1
+
+		ls := newTestServer(t, WithConfig(cfg))
+
+		assert.Equal(t, http.StatusOK, postBatch(t, ls, `[{"name": "Grace"}]`).Code)
+		assert.Equal(t, http.StatusBadRequest, postBatch(t, ls, `[{"name": "Grace"}, {"name": "Ada"}]`).Code)
+	})
+}
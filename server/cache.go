@@ -0,0 +1,117 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats are the render cache counters
+type CacheStats struct {
+	Hits    uint64 // requests served from the cache
+	Misses  uint64 // requests that had to render the avatar
+	Entries int    // cached avatars
+	Bytes   int64  // total size of the cached avatars
+}
+
+// renderCache is a concurrency-safe LRU cache of encoded avatars,
+// bounded by both the entry count and their total size
+type renderCache struct {
+	maxEntries int
+	maxBytes   int64
+
+	mu      sync.Mutex
+	hits    uint64
+	misses  uint64
+	bytes   int64
+	order   *list.List               // front is the most recently used
+	entries map[string]*list.Element // key -> *cacheEntry element
+}
+
+// cacheEntry is a single cached avatar
+type cacheEntry struct {
+	key  string
+	body []byte
+}
+
+// newRenderCache creates a new render cache with the given limits
+func newRenderCache(maxEntries int, maxBytes int64) *renderCache {
+	return &renderCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// get fetches the cached avatar, and marks it as recently used.
+// The returned body must not be modified
+func (c *renderCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+
+		return nil, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(el)
+
+	return el.Value.(*cacheEntry).body, true
+}
+
+// add caches the avatar, evicting the least recently used ones
+// until the cache is back within its limits.
+// Avatars larger than the entire cache are not cached
+func (c *renderCache) add(key string, body []byte) {
+	size := int64(len(body))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+
+		c.bytes += size - int64(len(entry.body))
+		entry.body = body
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[key] = c.order.PushFront(&cacheEntry{key: key, body: body})
+		c.bytes += size
+	}
+
+	for c.order.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.evict()
+	}
+}
+
+// evict removes the least recently used avatar
+func (c *renderCache) evict() {
+	el := c.order.Back()
+	if el == nil {
+		return
+	}
+
+	entry := c.order.Remove(el).(*cacheEntry)
+
+	delete(c.entries, entry.key)
+	c.bytes -= int64(len(entry.body))
+}
+
+// stats returns the current cache counters
+func (c *renderCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: c.order.Len(),
+		Bytes:   c.bytes,
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hits and misses", func(t *testing.T) {
+		t.Parallel()
+
+		c := newRenderCache(10, 1024)
+
+		_, ok := c.get("a")
+		assert.False(t, ok)
+
+		c.add("a", []byte("avatar"))
+
+		body, ok := c.get("a")
+		require.True(t, ok)
+		assert.Equal(t, []byte("avatar"), body)
+
+		assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Entries: 1, Bytes: 6}, c.stats())
+	})
+
+	t.Run("entry limit", func(t *testing.T) {
+		t.Parallel()
+
+		c := newRenderCache(2, 1024)
+
+		c.add("a", []byte("1"))
+		c.add("b", []byte("2"))
+
+		// Use a, so b is the least recently used
+		_, _ = c.get("a")
+
+		c.add("c", []byte("3"))
+
+		_, ok := c.get("b")
+		assert.False(t, ok)
+
+		for _, key := range []string{"a", "c"} {
+			_, ok := c.get(key)
+			assert.True(t, ok, key)
+		}
+
+		assert.Equal(t, 2, c.stats().Entries)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		t.Parallel()
+
+		c := newRenderCache(10, 8)
+
+		c.add("a", []byte("1234"))
+		c.add("b", []byte("1234"))
+		c.add("c", []byte("12"))
+
+		_, ok := c.get("a")
+		assert.False(t, ok)
+
+		assert.Equal(t, int64(6), c.stats().Bytes)
+
+		// Avatars larger than the cache are skipped
+		c.add("d", []byte("123456789"))
+
+		_, ok = c.get("d")
+		assert.False(t, ok)
+
+		assert.Equal(t, 2, c.stats().Entries)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		t.Parallel()
+
+		c := newRenderCache(10, 1024)
+
+		c.add("a", []byte("1234"))
+		c.add("a", []byte("12"))
+
+		body, ok := c.get("a")
+		require.True(t, ok)
+		assert.Equal(t, []byte("12"), body)
+
+		assert.Equal(t, int64(2), c.stats().Bytes)
+		assert.Equal(t, 1, c.stats().Entries)
+	})
+}
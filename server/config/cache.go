@@ -0,0 +1,31 @@
+package config
+
+const (
+	DefaultCacheMaxEntries       = 4096
+	DefaultCacheMaxBytes   int64 = 64 << 20 // 64 MiB
+)
+
+// Cache defines the in-memory avatar render cache configuration
+type Cache struct {
+	// Flag indicating if the render cache is disabled
+	Disabled bool `toml:"disabled"`
+
+	// The maximum number of cached avatars
+	MaxEntries int `toml:"max_entries"`
+
+	// The maximum total size of the cached avatars, in bytes
+	MaxBytes int64 `toml:"max_bytes"`
+}
+
+// DefaultCacheConfig returns the default render cache configuration
+func DefaultCacheConfig() *Cache {
+	return &Cache{
+		MaxEntries: DefaultCacheMaxEntries,
+		MaxBytes:   DefaultCacheMaxBytes,
+	}
+}
+
+// Enabled checks if the render cache is enabled
+func (c *Cache) Enabled() bool {
+	return c != nil && !c.Disabled
+}
@@ -2,15 +2,43 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"regexp"
+	"strings"
 
 	"github.com/pelletier/go-toml"
+	"github.com/sig-0/boring-avatars-go/avatars"
 )
 
-const DefaultListenAddress = "0.0.0.0:8545"
+const (
+	DefaultListenAddress = "0.0.0.0:8545"
+	DefaultMaxSize       = 512 // px
+	DefaultMaxBatchSize  = 100
+	DefaultHasher        = HasherJava
+)
 
-var ErrInvalidListenAddress = errors.New("invalid listen address")
+// Avatar seed hashers
+const (
+	HasherJava   = "java"
+	HasherFNV1a  = "fnv1a"
+	HasherSHA256 = "sha256"
+	HasherHMAC   = "hmac-sha256"
+)
+
+var (
+	ErrInvalidListenAddress = errors.New("invalid listen address")
+	ErrInvalidMaxSize       = errors.New("invalid max size")
+	ErrInvalidMaxBatchSize  = errors.New("invalid max batch size")
+	ErrInvalidHasher        = errors.New("invalid hasher")
+	ErrMissingHasherKey     = errors.New("missing hasher key")
+	ErrInvalidNormalize     = errors.New("invalid normalize steps")
+	ErrInvalidPalette       = errors.New("invalid palette")
+	ErrInvalidCache         = errors.New("invalid cache config")
+	ErrInvalidMetrics       = errors.New("invalid metrics config")
+	ErrInvalidTracing       = errors.New("invalid tracing config")
+)
 
 var listenAddressRegex = regexp.MustCompile(`^\d{1,3}(\.\d{1,3}){3}:\d+$`)
 
@@ -22,6 +50,42 @@ type Config struct {
 	// The address at which the server will be served.
 	// Format should be: <IP>:<PORT>
 	ListenAddress string `toml:"listen_address"`
+
+	// The maximum avatar width and height, in px.
+	// It applies to both SVGs and raster images
+	MaxSize int `toml:"max_size"`
+
+	// The maximum number of avatars in a single batch request
+	MaxBatchSize int `toml:"max_batch_size"`
+
+	// The hasher deriving avatar seeds from names:
+	// java (matches the JS library), fnv1a, sha256 or hmac-sha256.
+	// Changing it changes every avatar
+	Hasher string `toml:"hasher"`
+
+	// The secret key of the hmac-sha256 hasher
+	HasherKey string `toml:"hasher_key"`
+
+	// The default name normalization steps, applied before hashing:
+	// a comma separated list of trim, nfc, nfkc, casefold and email, or identity (all of them).
+	// Requests can override it with the normalize param
+	Normalize string `toml:"normalize"`
+
+	// Additional named palettes, available through the palette param.
+	// They take precedence over the built-in catalog palettes of the same name
+	Palettes map[string][]string `toml:"palettes"`
+
+	// The in-memory render cache config. The cache is enabled by default,
+	// and disabled by setting disabled (or by a nil config)
+	Cache *Cache `toml:"cache"`
+
+	// The Prometheus metrics endpoint config. The metrics are disabled by default,
+	// and enabled by setting disabled to false (on a non-nil config)
+	Metrics *Metrics `toml:"metrics"`
+
+	// The OpenTelemetry trace export config. Tracing is disabled by default,
+	// and enabled by setting an endpoint (on a non-nil config)
+	Tracing *Tracing `toml:"tracing"`
 }
 
 // DefaultConfig returns the default server configuration
@@ -29,6 +93,12 @@ func DefaultConfig() *Config {
 	return &Config{
 		ListenAddress: DefaultListenAddress,
 		CORSConfig:    DefaultCORSConfig(),
+		MaxSize:       DefaultMaxSize,
+		MaxBatchSize:  DefaultMaxBatchSize,
+		Hasher:        DefaultHasher,
+		Cache:         DefaultCacheConfig(),
+		Metrics:       DefaultMetricsConfig(),
+		Tracing:       DefaultTracingConfig(),
 	}
 }
 
@@ -39,6 +109,88 @@ func ValidateConfig(config *Config) error {
 		return ErrInvalidListenAddress
 	}
 
+	// Validate the max avatar size
+	if config.MaxSize <= 0 {
+		return ErrInvalidMaxSize
+	}
+
+	// Validate the max batch size
+	if config.MaxBatchSize <= 0 {
+		return ErrInvalidMaxBatchSize
+	}
+
+	// Validate the seed hasher
+	switch config.Hasher {
+	case HasherJava, HasherFNV1a, HasherSHA256:
+	case HasherHMAC:
+		if config.HasherKey == "" {
+			return ErrMissingHasherKey
+		}
+	default:
+		return ErrInvalidHasher
+	}
+
+	// Validate the name normalization steps
+	if _, err := avatars.ParseNormalization(config.Normalize); err != nil {
+		return fmt.Errorf("%w, %w", ErrInvalidNormalize, err)
+	}
+
+	// Validate the named palettes. They're looked up case-insensitively,
+	// so names differing only in case would overwrite each other
+	seen := make(map[string]string, len(config.Palettes))
+
+	for name, colors := range config.Palettes {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%w: empty name", ErrInvalidPalette)
+		}
+
+		key := strings.ToLower(strings.TrimSpace(name))
+		if other, dup := seen[key]; dup {
+			return fmt.Errorf("%w: %q and %q differ only in case", ErrInvalidPalette, min(name, other), max(name, other))
+		}
+
+		seen[key] = name
+
+		if err := avatars.ValidatePalette(colors); err != nil {
+			return fmt.Errorf("%w %q, %w", ErrInvalidPalette, name, err)
+		}
+	}
+
+	// Validate the render cache limits
+	if config.Cache.Enabled() {
+		if config.Cache.MaxEntries <= 0 {
+			return fmt.Errorf("%w: max entries must be positive", ErrInvalidCache)
+		}
+
+		if config.Cache.MaxBytes <= 0 {
+			return fmt.Errorf("%w: max bytes must be positive", ErrInvalidCache)
+		}
+	}
+
+	// Validate the metrics endpoint
+	if config.Metrics.Enabled() {
+		if !strings.HasPrefix(config.Metrics.Path, "/") {
+			return fmt.Errorf("%w: path must start with /", ErrInvalidMetrics)
+		}
+
+		addr := config.Metrics.ListenAddress
+		if addr != "" && (!listenAddressRegex.MatchString(addr) || addr == config.ListenAddress) {
+			return fmt.Errorf("%w: invalid listen address", ErrInvalidMetrics)
+		}
+	}
+
+	// Validate the trace export
+	if config.Tracing.Enabled() {
+		u, err := url.Parse(config.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: endpoint must be an http(s) URL", ErrInvalidTracing)
+		}
+
+		if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("%w: sample ratio must be between 0 and 1", ErrInvalidTracing)
+		}
+	}
+
 	return nil
 }
 
@@ -50,12 +202,15 @@ func Read(path string) (*Config, error) {
 		return nil, err
 	}
 
-	// Parse it
-	var cfg Config
+	// Parse it on top of the defaults of the options added after the listen address and CORS,
+	// so options missing from the file (or from a section) keep their default.
+	// Earlier config files keep their meaning: without a cors_config, CORS stays disabled
+	cfg := DefaultConfig()
+	cfg.ListenAddress, cfg.CORSConfig = "", nil
 
-	if err := toml.Unmarshal(content, &cfg); err != nil {
+	if err := toml.Unmarshal(content, cfg); err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_ValidateConfig(t *testing.T) {
@@ -18,9 +21,187 @@ func TestConfig_ValidateConfig(t *testing.T) {
 		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidListenAddress)
 	})
 
+	t.Run("invalid max size", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.MaxSize = 0
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidMaxSize)
+	})
+
+	t.Run("invalid max batch size", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.MaxBatchSize = 0
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidMaxBatchSize)
+	})
+
+	t.Run("invalid hasher", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Hasher = "md5"
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidHasher)
+	})
+
+	t.Run("missing hasher key", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Hasher = HasherHMAC
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrMissingHasherKey)
+	})
+
+	t.Run("invalid normalize steps", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Normalize = "trim,lowercase"
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidNormalize)
+	})
+
+	t.Run("invalid palette", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Palettes = map[string][]string{"brand": {"#123456", "red"}}
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidPalette)
+
+		// Names are case-insensitive
+		cfg.Palettes = map[string][]string{"Brand": {"#123456"}, "brand ": {"#654321"}}
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidPalette)
+	})
+
+	t.Run("invalid cache limits", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Cache.MaxBytes = 0
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidCache)
+
+		// The limits of a disabled cache don't matter
+		cfg.Cache.Disabled = true
+
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("invalid metrics config", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Metrics.Disabled = false
+		cfg.Metrics.Path = "metrics"
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidMetrics)
+
+		cfg = DefaultConfig()
+		cfg.Metrics.Disabled = false
+		cfg.Metrics.ListenAddress = cfg.ListenAddress
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidMetrics)
+
+		cfg.Metrics.ListenAddress = "0.0.0.0:9090"
+
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("invalid tracing config", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := DefaultConfig()
+		cfg.Tracing.Endpoint = "localhost:4318"
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidTracing)
+
+		cfg.Tracing.Endpoint = "http://localhost:4318"
+		cfg.Tracing.SampleRatio = 1.5
+
+		assert.ErrorIs(t, ValidateConfig(cfg), ErrInvalidTracing)
+
+		cfg.Tracing.SampleRatio = 0.25
+
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
 	t.Run("valid configuration", func(t *testing.T) {
 		t.Parallel()
 
 		assert.NoError(t, ValidateConfig(DefaultConfig()))
 	})
 }
+
+func TestConfig_Read(t *testing.T) {
+	t.Parallel()
+
+	read := func(t *testing.T, content string) *Config {
+		t.Helper()
+
+		path := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		cfg, err := Read(path)
+		require.NoError(t, err)
+		require.NoError(t, ValidateConfig(cfg))
+
+		return cfg
+	}
+
+	// Read leaves the listen address, which every config file has, without a default
+	const listen = "listen_address = \"0.0.0.0:8545\"\n"
+
+	t.Run("missing options", func(t *testing.T) {
+		t.Parallel()
+
+		expected := DefaultConfig()
+		expected.CORSConfig = nil
+
+		assert.Equal(t, expected, read(t, listen))
+	})
+
+	t.Run("earlier config file", func(t *testing.T) {
+		t.Parallel()
+
+		// A config file predating the other options keeps CORS disabled
+		cfg := read(t, "listen_address = \"127.0.0.1:8080\"\n")
+		assert.Nil(t, cfg.CORSConfig)
+		assert.Equal(t, "127.0.0.1:8080", cfg.ListenAddress)
+
+		// and its CORS config as is
+		cfg = read(t, listen+"[cors_config]\ncors_allowed_origins = [\"https://example.com\"]\n")
+		assert.Equal(t, &CORS{AllowedOrigins: []string{"https://example.com"}}, cfg.CORSConfig)
+	})
+
+	t.Run("partial sections", func(t *testing.T) {
+		t.Parallel()
+
+		// Setting a single key keeps the section defaults
+		cfg := read(t, listen+"[tracing]\nendpoint = \"http://localhost:4318\"\n")
+		assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+		assert.Equal(t, DefaultTracingServiceName, cfg.Tracing.ServiceName)
+
+		cfg = read(t, listen+"[cache]\nmax_entries = 10\n")
+		assert.Equal(t, 10, cfg.Cache.MaxEntries)
+		assert.Equal(t, DefaultCacheMaxBytes, cfg.Cache.MaxBytes)
+
+		cfg = read(t, listen+"[metrics]\nlisten_address = \"127.0.0.1:9090\"\n")
+		assert.Equal(t, DefaultMetricsPath, cfg.Metrics.Path)
+	})
+
+	t.Run("disabled sections", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := read(t, listen+"[cache]\ndisabled = true\n[metrics]\ndisabled = true\n")
+		assert.False(t, cfg.Cache.Enabled())
+		assert.False(t, cfg.Metrics.Enabled())
+		assert.False(t, cfg.Tracing.Enabled())
+	})
+}
@@ -0,0 +1,31 @@
+package config
+
+const DefaultMetricsPath = "/metrics"
+
+// Metrics defines the Prometheus metrics endpoint configuration
+type Metrics struct {
+	// Flag indicating if the metrics endpoint is disabled.
+	// The metrics include the Go runtime and process ones, so they're opt-in
+	Disabled bool `toml:"disabled" comment:"Set to false to serve the metrics (Go runtime and process ones included)"`
+
+	// The path the metrics are served at
+	Path string `toml:"path"`
+
+	// The address of a separate metrics listener, in the <IP>:<PORT> format.
+	// If empty, the metrics are served alongside the avatars, to anyone who can fetch them
+	ListenAddress string `toml:"listen_address" comment:"Preferably loopback. If empty, served publicly with the avatars"`
+}
+
+// DefaultMetricsConfig returns the default metrics configuration,
+// with the endpoint disabled
+func DefaultMetricsConfig() *Metrics {
+	return &Metrics{
+		Disabled: true,
+		Path:     DefaultMetricsPath,
+	}
+}
+
+// Enabled checks if the metrics endpoint is enabled
+func (m *Metrics) Enabled() bool {
+	return m != nil && !m.Disabled
+}
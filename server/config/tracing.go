@@ -0,0 +1,30 @@
+package config
+
+const DefaultTracingServiceName = "boring-avatars"
+
+// Tracing defines the OpenTelemetry trace export configuration
+type Tracing struct {
+	// The OTLP/HTTP collector endpoint URL the traces are exported to,
+	// such as http://localhost:4318. If empty, tracing is disabled
+	Endpoint string `toml:"endpoint"`
+
+	// The service name the traces are reported under
+	ServiceName string `toml:"service_name"`
+
+	// The ratio of traces sampled, between 0 and 1.
+	// Traces sampled by the caller are always kept
+	SampleRatio float64 `toml:"sample_ratio"`
+}
+
+// DefaultTracingConfig returns the default (disabled) tracing configuration
+func DefaultTracingConfig() *Tracing {
+	return &Tracing{
+		ServiceName: DefaultTracingServiceName,
+		SampleRatio: 1,
+	}
+}
+
+// Enabled checks if trace export is enabled
+func (t *Tracing) Enabled() bool {
+	return t != nil && t.Endpoint != ""
+}
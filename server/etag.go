@@ -0,0 +1,89 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sig-0/boring-avatars-go/avatars"
+)
+
+// avatarETag returns the strong ETag of the avatar, derived from the
+// normalized render params, the output format and the output version.
+// The avatar output is deterministic, so it doesn't need to be generated
+func avatarETag(opts avatars.Options, f format) string {
+	palette := opts.Palette
+	if len(palette) == 0 {
+		palette = avatars.DefaultPalette
+	}
+
+	h := sha256.New()
+
+	_, _ = fmt.Fprintf(
+		h,
+		"%d\x00%s\x00%d\x00%s\x00%d\x00%t\x00%s\x00%s\x00%t",
+		avatars.OutputVersion,
+		opts.Style,
+		opts.Seed(),
+		strings.Join(palette, ","),
+		opts.Size,
+		opts.Square,
+		f,
+		opts.IDPrefix,
+		opts.UniqueIDs,
+	)
+
+	// The shape params are only hashed if set, so the existing ETags stay the same
+	if opts.Shape != "" || opts.Radius != 0 {
+		_, _ = fmt.Fprintf(h, "\x00shape\x00%s\x00%g", opts.Shape, opts.Radius)
+	}
+
+	if opts.Unmasked || opts.Transparent {
+		_, _ = fmt.Fprintf(h, "\x00composite\x00%t\x00%t", opts.Unmasked, opts.Transparent)
+	}
+
+	// The default title is the raw name, which differs between
+	// names that normalize to the same seed
+	if a := opts.Accessibility; a != nil {
+		_, _ = fmt.Fprintf(h, "\x00%q\x00%t\x00%q\x00%q", a.Title, a.HideName, a.Desc, opts.Name)
+	}
+
+	// So are the initials, taken from the raw name
+	if opts.Initials {
+		_, _ = fmt.Fprintf(h, "\x00initials\x00%q", avatars.Initials(opts.Name))
+	}
+
+	// Raster images are always still, and in the main palette
+	if opts.Animate && !f.raster() {
+		_, _ = fmt.Fprint(h, "\x00animate")
+	}
+
+	if len(opts.DarkPalette) > 0 && !f.raster() {
+		_, _ = fmt.Fprintf(h, "\x00dark\x00%s", strings.Join(opts.DarkPalette, ","))
+	}
+
+	if b := opts.Border; b != nil {
+		_, _ = fmt.Fprintf(h, "\x00border\x00%g\x00%s", b.Width, b.Color)
+	}
+
+	if b := opts.Badge; b != nil {
+		_, _ = fmt.Fprintf(h, "\x00badge\x00%s\x00%s\x00%s\x00%g", b.Status, b.Color, b.Corner, b.Size)
+	}
+
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches checks if the If-None-Match header value matches the ETag.
+// It uses the weak comparison, as required for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// format is the avatar output format
+type format string
+
+const (
+	formatSVG  format = "svg"
+	formatPNG  format = "png"
+	formatWebP format = "webp"
+	formatJPEG format = "jpeg"
+)
+
+const jpegQuality = 90
+
+// formats are the supported output formats, in the order
+// they are preferred in when the client accepts several equally
+var formats = []format{formatSVG, formatPNG, formatWebP, formatJPEG}
+
+// contentType returns the format's media type
+func (f format) contentType() string {
+	switch f {
+	case formatPNG:
+		return "image/png"
+	case formatWebP:
+		return "image/webp"
+	case formatJPEG:
+		return "image/jpeg"
+	default:
+		return "image/svg+xml"
+	}
+}
+
+// raster checks if the format is a bitmap format
+func (f format) raster() bool {
+	return f != formatSVG
+}
+
+// parseFormat parses the format query param value
+func parseFormat(v string) (format, bool) {
+	switch f := format(strings.ToLower(v)); f {
+	case "jpg":
+		return formatJPEG, true
+	case formatSVG, formatPNG, formatWebP, formatJPEG:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// negotiateFormat picks the format the client prefers, from the Accept header.
+// SVG is served when the header is missing, or when nothing it lists is supported
+func negotiateFormat(accept string) format {
+	if strings.TrimSpace(accept) == "" {
+		return formatSVG
+	}
+
+	var (
+		best  = formatSVG
+		bestQ = -1.0
+	)
+
+	for _, f := range formats {
+		if q := acceptQuality(accept, f.contentType()); q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+
+	if bestQ <= 0 {
+		return formatSVG
+	}
+
+	return best
+}
+
+// acceptQuality returns the quality value the Accept header assigns to the media type.
+// The most specific matching range wins, and unmatched types get 0
+func acceptQuality(accept, mediaType string) float64 {
+	var (
+		kind, _, _ = strings.Cut(mediaType, "/")
+		q          = 0.0
+		precedence = -1
+	)
+
+	for _, part := range strings.Split(accept, ",") {
+		rng, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		var p int
+
+		switch rng {
+		case mediaType:
+			p = 2
+		case kind + "/*":
+			p = 1
+		case "*/*":
+			p = 0
+		default:
+			continue
+		}
+
+		if p < precedence {
+			continue
+		}
+
+		v := 1.0
+
+		if qv, ok := params["q"]; ok {
+			if v, err = strconv.ParseFloat(qv, 64); err != nil {
+				continue
+			}
+		}
+
+		q, precedence = v, p
+	}
+
+	return q
+}
+
+// formatFromRequest resolves the requested output format, either explicitly
+// through the format query param, or by negotiating it through the Accept header.
+// The flag indicates if the format was negotiated
+func formatFromRequest(r *http.Request) (format, bool, error) {
+	v := r.URL.Query().Get(formatParam)
+	if v == "" {
+		return negotiateFormat(r.Header.Get("Accept")), true, nil
+	}
+
+	f, ok := parseFormat(v)
+	if !ok {
+		return "", false, fmt.Errorf("invalid format %q (svg, png, webp, jpeg)", v)
+	}
+
+	return f, false, nil
+}
+
+// encodeImage encodes the image in the given raster format
+func encodeImage(w io.Writer, img image.Image, f format) error {
+	switch f {
+	case formatPNG:
+		return png.Encode(w, img)
+	case formatWebP:
+		return nativewebp.Encode(w, img, nil)
+	case formatJPEG:
+		// JPEGs have no alpha channel, so flatten the image onto white
+		flat := image.NewRGBA(img.Bounds())
+
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+	default:
+		return fmt.Errorf("unsupported raster format %q", f)
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace = "boring_avatars"
+
+	// unknownLabel is the variant / format label
+	// of requests rejected before they were parsed
+	unknownLabel = "unknown"
+)
+
+// metrics are the Prometheus avatar server metrics.
+// A nil *metrics is valid, and records nothing
+type metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	renderDuration  *prometheus.HistogramVec
+}
+
+// newMetrics creates the server metrics, on a dedicated registry
+func newMetrics(s *Server) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "The number of avatar requests, by variant, format and status.",
+		}, []string{"variant", "format", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "The avatar request latency, by variant, format and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"variant", "format", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_in_flight",
+			Help:      "The number of avatar requests being served.",
+		}),
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "render_duration_seconds",
+			Help:      "The time spent rendering avatars, by variant and format.",
+			Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+		}, []string{"variant", "format"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.inFlight,
+		m.renderDuration,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_hits_total",
+			Help:      "The number of avatars served from the render cache.",
+		}, func() float64 {
+			return float64(s.CacheStats().Hits)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_misses_total",
+			Help:      "The number of avatars missing from the render cache.",
+		}, func() float64 {
+			return float64(s.CacheStats().Misses)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_hit_ratio",
+			Help:      "The ratio of cache lookups that were hits, since the server started.",
+		}, func() float64 {
+			stats := s.CacheStats()
+			if stats.Hits+stats.Misses == 0 {
+				return 0
+			}
+
+			return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_entries",
+			Help:      "The number of avatars in the render cache.",
+		}, func() float64 {
+			return float64(s.CacheStats().Entries)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_bytes",
+			Help:      "The total size of the avatars in the render cache, in bytes.",
+		}, func() float64 {
+			return float64(s.CacheStats().Bytes)
+		}),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// handler returns the metrics endpoint handler
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// instrument is the avatar route middleware, recording the request metrics
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		var (
+			start  = time.Now()
+			labels = &requestLabels{variant: unknownLabel, format: unknownLabel}
+			ww     = middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		)
+
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), requestLabelsKey{}, labels)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		values := []string{labels.variant, labels.format, strconv.Itoa(status)}
+
+		m.requests.WithLabelValues(values...).Inc()
+		m.requestDuration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observeRender records the avatar render duration
+func (m *metrics) observeRender(variant string, f format, start time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.renderDuration.WithLabelValues(variant, string(f)).Observe(time.Since(start).Seconds())
+}
+
+type requestLabelsKey struct{}
+
+// requestLabels are the avatar request metric labels,
+// filled in by the handler once the request is parsed
+type requestLabels struct {
+	variant string
+	format  string
+}
+
+// setRequestLabels sets the metric labels of the request.
+// It's a no-op if the request isn't instrumented
+func setRequestLabels(ctx context.Context, variant string, f format) {
+	if labels, ok := ctx.Value(requestLabelsKey{}).(*requestLabels); ok {
+		labels.variant, labels.format = variant, string(f)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricsConfig returns the default config, with the metrics enabled
+func metricsConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Metrics.Disabled = false
+
+	return cfg
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("avatar requests", func(t *testing.T) {
+		t.Parallel()
+
+		s := newTestServer(t, WithConfig(metricsConfig()))
+
+		get(t, s, "/?name=Grace&variant=beam")
+		get(t, s, "/?name=Grace&variant=beam")
+		get(t, s, "/ring/Grace.png")
+		get(t, s, "/?variant=nope")
+
+		rec := get(t, s, "/metrics")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		body := rec.Body.String()
+
+		for _, line := range []string{
+			`boring_avatars_http_requests_total{format="svg",status="200",variant="beam"} 2`,
+			`boring_avatars_http_requests_total{format="png",status="200",variant="ring"} 1`,
+			`boring_avatars_http_requests_total{format="unknown",status="400",variant="unknown"} 1`,
+			`boring_avatars_http_request_duration_seconds_count{format="svg",status="200",variant="beam"} 2`,
+			`boring_avatars_render_duration_seconds_count{format="svg",variant="beam"} 1`,
+			`boring_avatars_render_duration_seconds_count{format="png",variant="ring"} 1`,
+			`boring_avatars_http_requests_in_flight 0`,
+			`boring_avatars_cache_hits_total 1`,
+			`boring_avatars_cache_misses_total 2`,
+			`boring_avatars_cache_hit_ratio 0.3333333333333333`,
+			`boring_avatars_cache_entries 2`,
+		} {
+			assert.Contains(t, body, line)
+		}
+	})
+
+	t.Run("custom path", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := metricsConfig()
+		cfg.Metrics.Path = "/internal/metrics"
+
+		s := newTestServer(t, WithConfig(cfg))
+
+		assert.Equal(t, http.StatusOK, get(t, s, "/internal/metrics").Code)
+	})
+
+	t.Run("separate listener", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := metricsConfig()
+		cfg.Metrics.ListenAddress = "127.0.0.1:9090"
+
+		s := newTestServer(t, WithConfig(cfg))
+
+		assert.NotEqual(t, http.StatusOK, get(t, s, "/metrics").Code)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		s := newTestServer(t)
+
+		assert.NotEqual(t, http.StatusOK, get(t, s, "/metrics").Code)
+		assert.Equal(t, http.StatusOK, get(t, s, "/?name=Grace").Code)
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sig-0/boring-avatars-go/avatars"
+)
+
+// namedPalette is a single named palette
+type namedPalette struct {
+	Name   string          `json:"name"`
+	Colors avatars.Palette `json:"colors"`
+}
+
+// palettesResponse lists the available named palettes
+type palettesResponse struct {
+	Palettes []namedPalette `json:"palettes"`
+}
+
+// configPalettes returns the named palettes from the (validated) config, keyed by lowercase name
+func configPalettes(palettes map[string][]string) map[string]avatars.Palette {
+	out := make(map[string]avatars.Palette, len(palettes))
+
+	for name, colors := range palettes {
+		out[strings.ToLower(strings.TrimSpace(name))] = colors
+	}
+
+	return out
+}
+
+// lookupPalette returns the named palette, from either the config or the catalog
+func (s *Server) lookupPalette(name string) (avatars.Palette, bool) {
+	if palette, ok := s.palettes[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return palette, true
+	}
+
+	return avatars.LookupPalette(name)
+}
+
+// palettesHandler serves
+// GET /palettes
+// listing the catalog palettes, followed by the other config palettes, sorted by name
+func (s *Server) palettesHandler(w http.ResponseWriter, _ *http.Request) {
+	names := avatars.Palettes()
+
+	for _, name := range slices.Sorted(maps.Keys(s.palettes)) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	resp := palettesResponse{
+		Palettes: make([]namedPalette, 0, len(names)),
+	}
+
+	for _, name := range names {
+		palette, _ := s.lookupPalette(name)
+
+		resp.Palettes = append(resp.Palettes, namedPalette{Name: name, Colors: palette})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// avatarPathHandler serves the path-based avatar URLs
+// GET /{variant}/{size}/{name}.{format}
+// GET /{variant}/{name}.{format}
+// by mapping them onto the query interface. Path params take precedence
+// over the query ones, which still apply for everything else (colors, square...).
+// The format extension is optional, and negotiated when missing
+func (s *Server) avatarPathHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		variant = urlParam(r, "variant")
+		size    = urlParam(r, "size")
+		file    = urlParam(r, "file")
+		q       = r.URL.Query()
+	)
+
+	// Split off the format extension, if it's a known one
+	name := file
+	if ext := path.Ext(file); ext != "" {
+		if f, ok := parseFormat(ext[1:]); ok {
+			name = strings.TrimSuffix(file, ext)
+
+			q.Set(formatParam, string(f))
+		}
+	}
+
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+
+		return
+	}
+
+	q.Set(variantParam, variant)
+	q.Set(nameParam, name)
+
+	if size != "" {
+		q.Set(sizeParam, size)
+	}
+
+	// Serve it like the equivalent query URL
+	r2 := r.Clone(r.Context())
+	r2.URL.RawQuery = q.Encode()
+
+	s.avatarHandler(w, r2)
+}
+
+// urlParam returns the unescaped path param
+func urlParam(r *http.Request, key string) string {
+	v := chi.URLParam(r, key)
+
+	// Params are only escaped when routing on the raw path
+	if r.URL.RawPath == "" {
+		return v
+	}
+
+	if unescaped, err := url.PathUnescape(v); err == nil {
+		return unescaped
+	}
+
+	return v
+}
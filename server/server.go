@@ -13,7 +13,11 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v3"
 	"github.com/rs/cors"
+	"github.com/sig-0/boring-avatars-go/avatars"
 	"github.com/sig-0/boring-avatars-go/server/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -22,6 +26,15 @@ var noopLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
 type Server struct {
 	logger *slog.Logger
 	config *config.Config
+	hasher avatars.Hasher
+
+	normalize avatars.Normalization      // default name normalization
+	palettes  map[string]avatars.Palette // named palettes from the config
+	cache     *renderCache               // nil if disabled
+	metrics   *metrics                   // nil if disabled
+
+	tracer         trace.Tracer
+	tracerProvider *sdktrace.TracerProvider // nil if disabled
 
 	mux         *chi.Mux
 	middlewares []Middleware
@@ -45,6 +58,29 @@ func New(opts ...Option) (*Server, error) {
 		return nil, fmt.Errorf("invalid configuration, %w", err)
 	}
 
+	s.hasher = newHasher(s.config)
+	s.normalize, _ = avatars.ParseNormalization(s.config.Normalize) // validated
+	s.palettes = configPalettes(s.config.Palettes)
+
+	if s.config.Cache.Enabled() {
+		s.cache = newRenderCache(s.config.Cache.MaxEntries, s.config.Cache.MaxBytes)
+	}
+
+	if s.config.Metrics.Enabled() {
+		s.metrics = newMetrics(s)
+	}
+
+	s.tracer = noop.NewTracerProvider().Tracer(tracerName)
+
+	if s.config.Tracing.Enabled() {
+		tp, err := newTracerProvider(s.config.Tracing)
+		if err != nil {
+			return nil, fmt.Errorf("unable to set up tracing, %w", err)
+		}
+
+		s.tracer, s.tracerProvider = tp.Tracer(tracerName), tp
+	}
+
 	// Set up the CORS middleware
 	if s.config.CORSConfig != nil {
 		corsMiddleware := cors.New(cors.Options{
@@ -75,43 +111,90 @@ func New(opts ...Option) (*Server, error) {
 		writer.WriteHeader(http.StatusOK)
 	})
 
-	// Register the avatar handler
-	s.mux.Get("/", avatarHandler)
+	// Register the metrics handler, unless it has its own listener
+	if s.metrics != nil && s.config.Metrics.ListenAddress == "" {
+		s.mux.Handle(s.config.Metrics.Path, s.metrics.handler())
+	}
+
+	// Register the avatar handlers
+	s.mux.Get("/styles", s.stylesHandler)
+	s.mux.Get("/palettes", s.palettesHandler)
+
+	var avatarMiddlewares []Middleware
+
+	if s.metrics != nil {
+		avatarMiddlewares = append(avatarMiddlewares, s.metrics.instrument)
+	}
+
+	if s.tracerProvider != nil {
+		avatarMiddlewares = append(avatarMiddlewares, s.traceRequest)
+	}
+
+	avatarRoutes := s.mux.With(avatarMiddlewares...)
+
+	avatarRoutes.Get("/", s.avatarHandler)
+	avatarRoutes.Get("/{variant}/{size}/{file}", s.avatarPathHandler)
+	avatarRoutes.Get("/{variant}/{file}", s.avatarPathHandler)
+
+	// Register the multi-avatar handlers
+	multiRoutes := chi.Router(s.mux)
+	if s.tracerProvider != nil {
+		multiRoutes = s.mux.With(s.traceRequest)
+	}
+
+	multiRoutes.Post("/batch", s.batchHandler)
+	multiRoutes.Get("/sprite", s.spriteHandler)
 
 	return s, nil
 }
 
-// Serve serves the avatar generation server
+// Serve serves the avatar generation server,
+// along with the separate metrics server, if configured
 func (s *Server) Serve(ctx context.Context) error {
-	server := &http.Server{
-		Addr:              s.config.ListenAddress,
-		Handler:           s.mux,
-		ReadHeaderTimeout: 60 * time.Second,
+	servers := []*http.Server{
+		{
+			Addr:              s.config.ListenAddress,
+			Handler:           s.mux,
+			ReadHeaderTimeout: 60 * time.Second,
+		},
+	}
+
+	if s.metrics != nil && s.config.Metrics.ListenAddress != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle(s.config.Metrics.Path, s.metrics.handler())
+
+		servers = append(servers, &http.Server{
+			Addr:              s.config.Metrics.ListenAddress,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 60 * time.Second,
+		})
 	}
 
 	group, gCtx := errgroup.WithContext(ctx)
 
-	group.Go(func() error {
-		defer s.logger.Info("server shut down")
+	for _, server := range servers {
+		group.Go(func() error {
+			defer s.logger.Info("server shut down", "address", server.Addr)
 
-		ln, err := net.Listen("tcp", server.Addr)
-		if err != nil {
-			return err
-		}
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
 
-		s.logger.Info(
-			fmt.Sprintf(
-				"server started at %s",
-				ln.Addr().String(),
-			),
-		)
+			s.logger.Info(
+				fmt.Sprintf(
+					"server started at %s",
+					ln.Addr().String(),
+				),
+			)
 
-		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			return err
-		}
+			if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
 
-		return nil
-	})
+			return nil
+		})
+	}
 
 	group.Go(func() error {
 		<-gCtx.Done()
@@ -121,8 +204,43 @@ func (s *Server) Serve(ctx context.Context) error {
 		wsCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 		defer cancel()
 
-		return server.Shutdown(wsCtx)
+		var errs []error
+
+		for _, server := range servers {
+			errs = append(errs, server.Shutdown(wsCtx))
+		}
+
+		// Flush the pending spans
+		if s.tracerProvider != nil {
+			errs = append(errs, s.tracerProvider.Shutdown(wsCtx))
+		}
+
+		return errors.Join(errs...)
 	})
 
 	return group.Wait()
 }
+
+// CacheStats returns the render cache counters.
+// They are all zero if the cache is disabled
+func (s *Server) CacheStats() CacheStats {
+	if s.cache == nil {
+		return CacheStats{}
+	}
+
+	return s.cache.stats()
+}
+
+// newHasher returns the avatar seed hasher for the (validated) configuration
+func newHasher(cfg *config.Config) avatars.Hasher {
+	switch cfg.Hasher {
+	case config.HasherFNV1a:
+		return avatars.FNV1aHasher{}
+	case config.HasherSHA256:
+		return avatars.SHA256Hasher{}
+	case config.HasherHMAC:
+		return avatars.HMACHasher{Key: []byte(cfg.HasherKey)}
+	default:
+		return avatars.JavaHasher{}
+	}
+}
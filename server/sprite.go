@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sig-0/boring-avatars-go/avatars/scene"
+)
+
+const namesParam = "names"
+
+// spriteHandler serves
+// GET /sprite?names&variant&colors&palette&square&normalize
+// as a single SVG sprite sheet, holding a <symbol id="avatar-{name}"> per name.
+// The names are comma-separated, and the other params apply to every avatar
+func (s *Server) spriteHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		q     = r.URL.Query()
+		names = spriteNames(q[namesParam])
+	)
+
+	if len(names) == 0 || len(names) > s.config.MaxBatchSize {
+		http.Error(w, fmt.Sprintf("invalid number of names (1-%d)", s.config.MaxBatchSize), http.StatusBadRequest)
+
+		return
+	}
+
+	symbols := make([]scene.Symbol, 0, len(names))
+
+	for _, name := range names {
+		q.Set(nameParam, name)
+
+		opts, _, err := s.parseAvatarOptions(ctx, q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		// The symbol IDs are unique, so they make for unique ID prefixes
+		id := symbolID(name)
+		opts.IDPrefix = id + "_"
+
+		sc, err := s.generateAvatar(ctx, opts)
+		if err != nil {
+			s.logger.Error("unable to generate avatar", "style", opts.Style, "err", err)
+
+			http.Error(w, "unable to generate avatar", http.StatusInternalServerError)
+
+			return
+		}
+
+		symbols = append(symbols, scene.Symbol{ID: id, Scene: sc})
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	setCacheHeaders(w, "")
+
+	_ = scene.WriteSprite(w, symbols)
+}
+
+// spriteNames splits the names params, dropping empty and duplicate names
+func spriteNames(values []string) []string {
+	var (
+		names []string
+		seen  = make(map[string]struct{})
+	)
+
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if _, ok := seen[name]; ok || name == "" {
+				continue
+			}
+
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// symbolID returns the sprite symbol ID of the name: "avatar-" followed by the name,
+// with every byte outside [A-Za-z0-9-] (including underscores) escaped as _XX.
+// The escaping is reversible, so different names never share an ID
+func symbolID(name string) string {
+	var b strings.Builder
+
+	b.WriteString("avatar-")
+
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+			b.WriteByte(c)
+		default:
+			_, _ = fmt.Fprintf(&b, "_%02X", c)
+		}
+	}
+
+	return b.String()
+}
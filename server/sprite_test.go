@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpriteHandler(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+
+	t.Run("symbols", func(t *testing.T) {
+		t.Parallel()
+
+		// Aa and BB share a seed, so their mask IDs would collide
+		rec := get(t, s, "/sprite?names=Aa,BB,Grace%20Hopper,Aa&variant=marble")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/svg+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		out := rec.Body.String()
+
+		assert.True(t, strings.HasPrefix(out, `<svg xmlns="http://www.w3.org/2000/svg"><symbol id="avatar-Aa"`))
+
+		for _, id := range []string{"avatar-Aa", "avatar-BB", "avatar-Grace_20Hopper"} {
+			assert.Equal(t, 1, strings.Count(out, `<symbol id="`+id+`"`), id)
+			assert.Equal(t, 1, strings.Count(out, `<mask id="`+id+`_mask_marble_`), id)
+			assert.Equal(t, 1, strings.Count(out, `<filter id="`+id+`_filter_mask_marble_`), id)
+		}
+	})
+
+	t.Run("labelled symbols", func(t *testing.T) {
+		t.Parallel()
+
+		out := get(t, s, "/sprite?names=Aa,BB&a11y=true&desc=A%20marble").Body.String()
+
+		for _, name := range []string{"Aa", "BB"} {
+			assert.Regexp(t, `<symbol id="avatar-`+name+`" [^>]*><title id="avatar-`+name+`_title_marble_\d+">`+name+
+				`</title><desc id="avatar-`+name+`_desc_marble_\d+">A marble</desc>`, out)
+		}
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		t.Parallel()
+
+		for _, target := range []string{
+			"/sprite",
+			"/sprite?names=,",
+			"/sprite?names=a,b&variant=nope",
+			"/sprite?names=a,b&colors=nope",
+		} {
+			assert.Equal(t, http.StatusBadRequest, get(t, s, target).Code, target)
+		}
+	})
+
+	t.Run("max names", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.DefaultConfig()
+		cfg.MaxBatchSize = 2
+
+		ls := newTestServer(t, WithConfig(cfg))
+
+		assert.Equal(t, http.StatusOK, get(t, ls, "/sprite?names=a,b").Code)
+		assert.Equal(t, http.StatusBadRequest, get(t, ls, "/sprite?names=a,b,c").Code)
+	})
+}
+
+func TestSymbolID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "avatar-a", symbolID("a"))
+	assert.Equal(t, "avatar-Grace-Hopper", symbolID("Grace-Hopper"))
+	assert.Equal(t, "avatar-grace_40example_2Ecom", symbolID("grace@example.com"))
+
+	// Underscores are escaped too, so escapes can't collide with names
+	assert.NotEqual(t, symbolID("a_20b"), symbolID("a b"))
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sig-0/boring-avatars-go/avatars"
+)
+
+// stylesResponse lists the available avatar styles
+type stylesResponse struct {
+	Styles []avatars.Style `json:"styles"`
+}
+
+// stylesHandler serves
+// GET /styles
+func (s *Server) stylesHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(stylesResponse{Styles: avatars.Styles()})
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sig-0/boring-avatars-go/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testCollector is a local OTLP/HTTP trace collector stand-in
+type testCollector struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+// newTestCollector starts a new test collector
+func newTestCollector(t *testing.T) *testCollector {
+	t.Helper()
+
+	c := &testCollector{}
+
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		var req collectortrace.ExportTraceServiceRequest
+
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, rs := range req.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				c.spans = append(c.spans, ss.GetSpans()...)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(nil)
+	}))
+
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+// spanNames returns the received span names, by trace ID
+func (c *testCollector) spanNames() map[string][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make(map[string][]string)
+
+	for _, span := range c.spans {
+		traceID := hex.EncodeToString(span.GetTraceId())
+
+		names[traceID] = append(names[traceID], span.GetName())
+	}
+
+	return names
+}
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	const (
+		traceID     = "0af7651916cd43dd8448eb211c80319c"
+		traceparent = "00-" + traceID + "-b7ad6b7169203331-01"
+	)
+
+	collector := newTestCollector(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Tracing.Endpoint = collector.URL
+
+	var logs bytes.Buffer
+
+	s := newTestServer(
+		t,
+		WithConfig(cfg),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))),
+	)
+
+	t.Cleanup(func() {
+		_ = s.tracerProvider.Shutdown(context.Background())
+	})
+
+	rec := get(t, s, "/beam/Grace.png?palette=nice-1", "traceparent", traceparent)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.NoError(t, s.tracerProvider.ForceFlush(context.Background()))
+
+	assert.ElementsMatch(
+		t,
+		[]string{
+			"validate palette",
+			"parse request",
+			"generate beam",
+			"encode png",
+			"GET /{variant}/{file}",
+		},
+		collector.spanNames()[traceID],
+	)
+
+	// The request log is correlated with the trace
+	assert.Contains(t, logs.String(), `"trace_id":"`+traceID+`"`)
+}
@@ -0,0 +1,47 @@
+// Renders every case in the parity manifest with the reference boring-avatars
+// library, overwriting the golden files and stamping the manifest generator.
+//
+// Usage: node generate.mjs [corpus dir]
+import { mkdir, readFile, writeFile } from 'node:fs/promises';
+import path from 'node:path';
+import { fileURLToPath } from 'node:url';
+
+import { createElement } from 'react';
+import { renderToStaticMarkup } from 'react-dom/server';
+import * as boring from 'boring-avatars';
+
+const here = path.dirname(fileURLToPath(import.meta.url));
+const dir = path.resolve(process.argv[2] ?? path.join(here, '../../avatars/testdata/parity'));
+
+// The CommonJS build is wrapped once more when imported as ESM
+const Avatar = boring.default?.default ?? boring.default;
+
+const pkg = JSON.parse(
+  await readFile(path.join(here, 'node_modules/boring-avatars/package.json'), 'utf8'),
+);
+
+const manifestPath = path.join(dir, 'manifest.json');
+const manifest = JSON.parse(await readFile(manifestPath, 'utf8'));
+
+for (const c of manifest.cases) {
+  const svg = renderToStaticMarkup(
+    createElement(Avatar, {
+      name: c.name,
+      variant: c.style,
+      colors: c.colors,
+      size: c.size,
+      square: c.square,
+    }),
+  );
+
+  const out = path.join(dir, c.file);
+
+  await mkdir(path.dirname(out), { recursive: true });
+  await writeFile(out, svg + '\n');
+}
+
+manifest.generator = `boring-avatars@${pkg.version}`;
+
+await writeFile(manifestPath, JSON.stringify(manifest, null, 2) + '\n');
+
+console.log(`Rendered ${manifest.cases.length} cases with ${manifest.generator}`);
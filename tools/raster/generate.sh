@@ -0,0 +1,26 @@
+#!/bin/sh
+# Renders the reference images of the raster tests from their SVG inputs with resvg,
+# an independent renderer, overwriting the PNGs next to them.
+#
+# Usage: ./generate.sh [testdata dir]
+set -eu
+
+here=$(dirname "$0")
+dir=${1:-"$here/../../avatars/raster/testdata"}
+
+# The pinned renderer version, since anti-aliasing changes between releases
+version=0.45.1
+
+if ! command -v resvg >/dev/null; then
+  echo "resvg not found, install it with: cargo install resvg --version $version" >&2
+  exit 1
+fi
+
+if [ "$(resvg --version)" != "$version" ]; then
+  echo "resvg $(resvg --version) found, want $version" >&2
+  exit 1
+fi
+
+for svg in "$dir"/*.svg; do
+  resvg "$svg" "${svg%.svg}.png"
+done